@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Prices effective at a point in time are the most
+// recent at or before it
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice WHERE name = 'Griselbrand'
+//  	AND time = (SELECT max(time) FROM prices.mtgprice
+//  		WHERE name = 'Griselbrand' AND time <= $1);
+func TestNameTimeProjectionAsOf(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	db := NewPriceDB()
+	db.Push(tuples[:8])
+	proj := NameTimeProjectionFromPriceDB(db)
+	proj.Append(tuples[8:])
+
+	// Exactly on the second scrape
+	group := proj.AsOf("Griselbrand", tuples[5].Time)
+	if len(group) != 2 {
+		t.Fatalf("unexpected group %v", group)
+	}
+	for _, tuple := range group {
+		if !tuple.Time.Equal(tuples[5].Time) {
+			t.Fatalf("unexpected group %v", group)
+		}
+	}
+
+	// Between the second and third scrapes
+	group = proj.AsOf("Griselbrand", tuples[5].Time.Add(time.Hour))
+	if len(group) != 2 || !group[0].Time.Equal(tuples[5].Time) {
+		t.Fatalf("unexpected group %v", group)
+	}
+
+	// After the third scrape, which only lives in the delta
+	group = proj.AsOf("Griselbrand", tuples[10].Time.Add(time.Hour))
+	if len(group) != 1 || group[0].Price != 2200 {
+		t.Fatalf("unexpected group %v", group)
+	}
+
+	// Before any scrape
+	group = proj.AsOf("Griselbrand", tuples[0].Time.Add(-time.Hour))
+	if len(group) != 0 {
+		t.Fatalf("unexpected group %v", group)
+	}
+}
+
+// The all cards snapshot must agree with asking for
+// each card in turn
+func TestNameTimeProjectionAsOfAll(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	db := NewPriceDB()
+	db.Push(tuples[:8])
+	proj := NameTimeProjectionFromPriceDB(db)
+	proj.Append(tuples[8:])
+
+	instants := []time.Time{
+		tuples[0].Time.Add(-time.Hour),
+		tuples[0].Time,
+		tuples[5].Time.Add(time.Hour),
+		tuples[10].Time.Add(time.Hour),
+	}
+	names := []string{"Avacyn, Angel of Hope", "Griselbrand", "Windswept Heath"}
+	for _, when := range instants {
+		all := proj.AsOfAll(when)
+		for _, name := range names {
+			expected := proj.AsOf(name, when)
+			group := all[name]
+			if len(group) != len(expected) {
+				t.Fatalf("%v at %v: all found %v, single found %v",
+					name, when, group, expected)
+			}
+			for i := range expected {
+				if group[i] != expected[i] {
+					t.Fatalf("%v at %v: all found %v, single found %v",
+						name, when, group, expected)
+				}
+			}
+		}
+	}
+}
+
+// A group split across both stores comes back in time
+// order, as AsOfAll returns it
+func TestNameTimeProjectionAsOfSplitGroup(t *testing.T) {
+	scraped := time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	db := NewPriceDB()
+	db.Push([]PriceTuple{
+		{Name: "Griselbrand", Set: "Avacyn Restored", Price: 2100, Time: scraped},
+	})
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	// Within the same scrape, but earlier than the main row
+	proj.Append([]PriceTuple{
+		{Name: "Griselbrand", Set: "Avacyn Restored Foil", Price: 5200,
+			Time: scraped.Add(-30 * time.Second)},
+	})
+
+	group := proj.AsOf("Griselbrand", scraped)
+	all := proj.AsOfAll(scraped)["Griselbrand"]
+	if len(group) != 2 || len(all) != 2 {
+		t.Fatalf("unexpected groups %v and %v", group, all)
+	}
+	if group[0].Price != 5200 || group[0] != all[0] || group[1] != all[1] {
+		t.Fatalf("groups disagree on order, %v != %v", group, all)
+	}
+}
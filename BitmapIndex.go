@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/willf/bitset"
+)
+
+// A bitmap of rows for every code of a dictionary column
+//
+// Equality becomes a copy of a single bitmap and membership
+// a union of a few, rather than a scan each.
+type BitmapIndex struct {
+	// Indexed by code, nil for codes never seen
+	bitmaps []*bitset.BitSet
+}
+
+func newBitmapIndex() *BitmapIndex {
+	return &BitmapIndex{bitmaps: make([]*bitset.BitSet, 0)}
+}
+
+// Record a row holding a code
+func (index *BitmapIndex) add(row int, code uint32) {
+	for int(code) >= len(index.bitmaps) {
+		index.bitmaps = append(index.bitmaps, nil)
+	}
+	if index.bitmaps[code] == nil {
+		index.bitmaps[code] = bitset.New(0)
+	}
+	index.bitmaps[code].Set(uint(row))
+}
+
+// Forget a row holding a code
+func (index *BitmapIndex) remove(row int, code uint32) {
+	if int(code) < len(index.bitmaps) && index.bitmaps[code] != nil {
+		index.bitmaps[code].Clear(uint(row))
+	}
+}
+
+// Determine the rows holding a code, which must not be modified
+func (index *BitmapIndex) rows(code uint32) *bitset.BitSet {
+	if int(code) >= len(index.bitmaps) {
+		return nil
+	}
+	return index.bitmaps[code]
+}
+
+// Determine the memory held by the bitmaps in bytes
+func (index *BitmapIndex) size() int {
+	size := 0
+	for _, bitmap := range index.bitmaps {
+		if bitmap != nil {
+			size += len(bitmap.Bytes()) * 8
+		}
+	}
+	return size
+}
+
+// Build a bitmap index over the column's current values
+//
+// Once built, the index is maintained by every Push and Set
+// until dropped. Building an existing index rebuilds it.
+func (c *FiniteString32Column) BuildIndex() {
+	c.index = newBitmapIndex()
+
+	row := 0
+	for _, chunk := range c.contents.chunks {
+		for _, code := range chunk {
+			c.index.add(row, code)
+			row++
+		}
+	}
+}
+
+// Discard the column's bitmap index, returning to scans
+func (c *FiniteString32Column) DropIndex() {
+	c.index = nil
+}
+
+// Determine if the column has a bitmap index
+func (c *FiniteString32Column) Indexed() bool {
+	return c.index != nil
+}
+
+// Determine the memory held by the column's bitmap
+// index in bytes, zero without one
+func (c *FiniteString32Column) IndexSize() int {
+	if c.index == nil {
+		return 0
+	}
+	return c.index.size()
+}
+
+// Answer Equal from the bitmap index
+func (c *FiniteString32Column) indexEqual(code uint32, found bool) BoolColumn {
+	results := c.indexResults()
+	if rows := c.index.rows(code); found && rows != nil {
+		results.contents.InPlaceUnion(rows)
+	}
+	c.contents.deleted.mask(results)
+
+	return results
+}
+
+// Answer Within from the bitmap index
+func (c *FiniteString32Column) indexWithin(values []string) BoolColumn {
+	results := c.indexResults()
+	for _, v := range values {
+		code, found := c.lookup(v)
+		if rows := c.index.rows(code); found && rows != nil {
+			results.contents.InPlaceUnion(rows)
+		}
+	}
+	c.contents.deleted.mask(results)
+
+	return results
+}
+
+// Create an all false result covering every row
+//
+// A bitmap only reaches its code's last row, so results
+// are sized for the column before bitmaps are merged in,
+// leaving Not to negate the rows past it.
+func (c *FiniteString32Column) indexResults() BoolColumn {
+	length := c.contents.length
+	return BoolColumnFromWords(make([]uint64, (length+63)/64), length)
+}
+
+// Determine the column of the database holding a field,
+// for fields stored as dictionary codes
+func (db *PriceDB) dictionaryColumn(field PriceField) (*FiniteString32Column, error) {
+	switch field {
+	case FieldName:
+		return &db.Names, nil
+	case FieldSet:
+		return &db.Sets, nil
+	}
+	return nil, fmt.Errorf("'%v' is not dictionary encoded", field)
+}
+
+// Build a bitmap index on the names or sets of the database
+func (db *PriceDB) BuildIndex(field PriceField) error {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	column, err := db.dictionaryColumn(field)
+	if err != nil {
+		return err
+	}
+	column.BuildIndex()
+
+	return nil
+}
+
+// Drop a bitmap index on the names or sets of the database
+func (db *PriceDB) DropIndex(field PriceField) error {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	column, err := db.dictionaryColumn(field)
+	if err != nil {
+		return err
+	}
+	column.DropIndex()
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Compare selections by their truthy indices
+func sameSelection(a, b BoolColumn) bool {
+	left, right := a.TruthyIndices(), b.TruthyIndices()
+	if len(left) != len(right) || a.end != b.end {
+		return false
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBitmapIndexMatchesScan(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	names := []string{"Griselbrand", "Windswept Heath", "Aether Vial"}
+
+	scanned := make([]BoolColumn, 0)
+	for _, name := range names {
+		scanned = append(scanned, db.Names.Equal(name))
+	}
+	scannedWithin := db.Names.Within(names[:2])
+
+	if err := db.BuildIndex(FieldName); err != nil {
+		t.Fatalf("failed to build index: %v", err)
+	}
+	if !db.Names.Indexed() || db.Names.IndexSize() == 0 {
+		t.Fatalf("index not built")
+	}
+
+	for i, name := range names {
+		if !sameSelection(db.Names.Equal(name), scanned[i]) {
+			t.Fatalf("indexed equal differs for %v", name)
+		}
+	}
+	if !sameSelection(db.Names.Within(names[:2]), scannedWithin) {
+		t.Fatalf("indexed within differs")
+	}
+
+	if err := db.DropIndex(FieldName); err != nil {
+		t.Fatalf("failed to drop index: %v", err)
+	}
+	if db.Names.Indexed() || db.Names.IndexSize() != 0 {
+		t.Fatalf("index not dropped")
+	}
+
+	if err := db.BuildIndex(FieldPrice); err == nil {
+		t.Fatalf("indexed a price column")
+	}
+}
+
+func TestBitmapIndexMaintained(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	db.BuildIndex(FieldSet)
+
+	// Pushes are indexed
+	db.Push([]PriceTuple{{Name: "Aether Vial", Set: "Darksteel",
+		Price: 500, Time: time.Unix(0, 0)}})
+	darksteel := db.Sets.Equal("Darksteel")
+	if indices := darksteel.TruthyIndices(); len(indices) != 1 || indices[0] != 12 {
+		t.Fatalf("push not indexed, %v", indices)
+	}
+
+	// Updates move rows between bitmaps
+	db.Update(db.Sets.Equal("Onslaught Foil"), FieldSet, "Darksteel")
+	darksteel = db.Sets.Equal("Darksteel")
+	if indices := darksteel.TruthyIndices(); len(indices) != 2 || indices[0] != 11 {
+		t.Fatalf("update not indexed, %v", indices)
+	}
+	foil := db.Sets.Equal("Onslaught Foil")
+	if len(foil.TruthyIndices()) != 0 {
+		t.Fatalf("update left stale bitmap")
+	}
+
+	// Deletes are masked and compaction reindexes
+	db.Delete(db.Names.Equal("Griselbrand"))
+	restored := db.Sets.Within([]string{"Avacyn Restored", "Avacyn Restored Foil"})
+	if len(restored.TruthyIndices()) != 3 {
+		t.Fatalf("deleted rows selected, %v", restored.TruthyIndices())
+	}
+	db.Compact()
+	restored = db.Sets.Within([]string{"Avacyn Restored", "Avacyn Restored Foil"})
+	if indices := restored.TruthyIndices(); len(indices) != 3 || indices[2] != 5 {
+		t.Fatalf("bad compacted index, %v", indices)
+	}
+}
+
+// Results from the index cover every row, so negating them
+// agrees with negating a scan
+func TestBitmapIndexNot(t *testing.T) {
+	col := NewFiniteString32Column()
+	col.Push([]string{"a", "b", "b", "b", "b"})
+
+	scanned := col.Equal("a")
+	scannedWithin := col.Within([]string{"a", "Zzz"})
+	col.BuildIndex()
+	indexed := col.Equal("a")
+	indexedWithin := col.Within([]string{"a", "Zzz"})
+
+	pairs := [][2]BoolColumn{{scanned, indexed}, {scannedWithin, indexedWithin}}
+	for _, pair := range pairs {
+		expected := pair[0].Not()
+		computed := pair[1].Not()
+		if !reflect.DeepEqual(computed.TruthyIndices(), expected.TruthyIndices()) {
+			t.Fatalf("negated index results %v, scan %v",
+				computed.TruthyIndices(), expected.TruthyIndices())
+		}
+	}
+}
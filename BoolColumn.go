@@ -14,30 +14,90 @@ type BoolColumn struct {
 
 func NewBoolColumn() BoolColumn {
 	return BoolColumn{
-		contents: bitset.New(1000000),
+		contents: bitset.New(0),
 		end:      0,
 	}
 }
 
-func (c *BoolColumn) Push(values []bool) {
+// Create a column holding length values packed 64 to a word,
+// as produced by predicate kernels
+//
+// The column takes ownership of words. Bits past length
+// are cleared.
+func BoolColumnFromWords(words []uint64, length int) BoolColumn {
+	if tail := uint(length) % 64; tail != 0 {
+		words[length/64] &= 1<<tail - 1
+	}
+
+	return BoolColumn{
+		contents: bitset.From(words),
+		end:      uint(length),
+	}
+}
 
+// Push values onto the column
+//
+// Room is reserved once up front, so each value costs
+// a single word update.
+func (c *BoolColumn) Push(values []bool) {
 	start := c.end
+	c.reserve(start + uint(len(values)))
+	set := c.contents.Bytes()
+
 	for i, v := range values {
-		c.contents.SetTo(start+uint(i), v)
+		pos := start + uint(i)
+		if v {
+			set[pos/64] |= 1 << (pos % 64)
+		} else {
+			set[pos/64] &^= 1 << (pos % 64)
+		}
 	}
 
 	c.end += uint(len(values))
 }
 
+// Push length values packed 64 to a word onto the column
+//
+// Typical usage is stitching on the result of a separately
+// stored range of rows, as projections do for their delta.
+// When the column ends on a word boundary the words are
+// copied directly, otherwise each is split across two.
+func (c *BoolColumn) PushWords(words []uint64, length int) {
+	start := c.end
+	c.reserve(start + uint(length))
+	set := c.contents.Bytes()
+
+	shift := start % 64
+	first := start / 64
+	for i := 0; i*64 < length; i++ {
+		word := words[i]
+		if remaining := length - i*64; remaining < 64 {
+			word &= 1<<uint(remaining) - 1
+		}
+
+		if shift == 0 {
+			set[first+uint(i)] = word
+			continue
+		}
+
+		// Keep the bits already pushed in the low word
+		low := first + uint(i)
+		set[low] = set[low]&(1<<shift-1) | word<<shift
+		if high := low + 1; high < uint(len(set)) {
+			set[high] = word >> (64 - shift)
+		}
+	}
+
+	c.end += uint(length)
+}
+
 // Push a length of true booleans onto the column
 //
 // Useful for unpacking compressed runs.
 func (c *BoolColumn) PushTrue(length int) {
-
 	start := c.end
-	for i := 0; i < length; i++ {
-		c.contents.Set(start + uint(i))
-	}
+	c.reserve(start + uint(length))
+	c.fill(start, start+uint(length), true)
 	c.end += uint(length)
 }
 
@@ -45,18 +105,75 @@ func (c *BoolColumn) PushTrue(length int) {
 //
 // Useful for unpacking compressed runs.
 func (c *BoolColumn) PushFalse(length int) {
-
 	start := c.end
-	for i := 0; i < length; i++ {
-		c.contents.Clear(start + uint(i))
-	}
+	c.reserve(start + uint(length))
+	c.fill(start, start+uint(length), false)
 	c.end += uint(length)
 }
 
+// Ensure the underlying set has room for values up to end
+//
+// The set grows in place, so copies of this column sharing
+// it see the growth, and at least doubles to keep repeated
+// pushes cheap.
+func (c *BoolColumn) reserve(end uint) {
+	set := c.contents.Bytes()
+	need := int((end + 63) / 64)
+	if need <= len(set) {
+		return
+	}
+
+	size := 2 * len(set)
+	if size < need {
+		size = need
+	}
+	grown := make([]uint64, size)
+	copy(grown, set)
+	*c.contents = *bitset.From(grown)
+}
+
+// Set or clear every value in [start, end), a whole word at a
+// time where the range covers one
+//
+// The set must already have room for end.
+func (c *BoolColumn) fill(start, end uint, value bool) {
+	if start >= end {
+		return
+	}
+	set := c.contents.Bytes()
+
+	first, last := start/64, (end-1)/64
+	for w := first; w <= last; w++ {
+		mask := ^uint64(0)
+		if w == first {
+			mask &= ^uint64(0) << (start % 64)
+		}
+		if w == last && end%64 != 0 {
+			mask &= 1<<(end%64) - 1
+		}
+
+		if value {
+			set[w] |= mask
+		} else {
+			set[w] &^= mask
+		}
+	}
+}
+
 // Negate every value of the column and return it
+//
+// Rows deleted from the table the column selects from become
+// selected, callers must filter them, as PriceDB's Not does.
 func (c *BoolColumn) Not() BoolColumn {
 	c.contents = c.contents.Complement()
 
+	// The underlying set may be longer than the values we
+	// pushed, those trailing bits must remain false
+	set := c.contents
+	for i, found := set.NextSet(c.end); found; i, found = set.NextSet(i + 1) {
+		set.Clear(i)
+	}
+
 	return *c
 }
 
@@ -90,6 +207,19 @@ func (c *BoolColumn) Clear(pos int) BoolColumn {
 	return *c
 }
 
+// Set a single value of this column
+//
+// Typical usage is to perform an in-place OR
+func (c *BoolColumn) Set(pos int) BoolColumn {
+
+	c.contents.Set(uint(pos))
+	if uint(pos) >= c.end {
+		c.end = uint(pos) + 1
+	}
+
+	return *c
+}
+
 // Returns all indices for which this column
 // has truthy values
 func (c *BoolColumn) TruthyIndices() []int {
@@ -105,3 +235,29 @@ func (c *BoolColumn) TruthyIndices() []int {
 
 	return indices
 }
+
+// Find the first truthy index at or after the provided index
+//
+// Allows walking a selection without allocating the full
+// list of TruthyIndices.
+func (c *BoolColumn) NextTruthy(index int) (int, bool) {
+	next, found := c.contents.NextSet(uint(index))
+	return int(next), found
+}
+
+// Set every value of this column in [start, end)
+//
+// Typical usage is marking a range found by a search
+// without touching the rest of the column
+func (c *BoolColumn) SetRange(start, end int) BoolColumn {
+
+	if start < end {
+		c.reserve(uint(end))
+		c.fill(uint(start), uint(end), true)
+	}
+	if end > 0 && uint(end) > c.end {
+		c.end = uint(end)
+	}
+
+	return *c
+}
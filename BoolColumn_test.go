@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+)
+
+// Check a column against the booleans it should hold
+func checkBools(t *testing.T, name string, c BoolColumn, expected []bool) {
+	if c.end != uint(len(expected)) {
+		t.Fatalf("%v: bad length, %v != %v", name, c.end, len(expected))
+	}
+	for i, v := range expected {
+		if c.contents.Test(uint(i)) != v {
+			t.Fatalf("%v: wrong value at %v", name, i)
+		}
+	}
+	if next, found := c.contents.NextSet(c.end); found {
+		t.Fatalf("%v: value set past the end at %v", name, next)
+	}
+}
+
+// Mixing every way of pushing at unaligned offsets must
+// match pushing one value at a time
+func TestBoolColumnWordPushes(t *testing.T) {
+	c := NewBoolColumn()
+	expected := make([]bool, 0)
+
+	pushed := make([]bool, 150)
+	for i := range pushed {
+		pushed[i] = i%3 == 0
+	}
+	c.Push(pushed[:5])
+	expected = append(expected, pushed[:5]...)
+
+	c.PushTrue(70)
+	for i := 0; i < 70; i++ {
+		expected = append(expected, true)
+	}
+
+	words := []uint64{0xF0F0F0F0F0F0F0F0, ^uint64(0), 0x5}
+	c.PushWords(words, 64*2+3)
+	for i := 0; i < 64*2+3; i++ {
+		expected = append(expected, words[i/64]&(1<<uint(i%64)) != 0)
+	}
+
+	c.PushFalse(200)
+	for i := 0; i < 200; i++ {
+		expected = append(expected, false)
+	}
+
+	c.Push(pushed)
+	expected = append(expected, pushed...)
+
+	c.PushTrue(1)
+	expected = append(expected, true)
+
+	checkBools(t, "mixed", c, expected)
+
+	// Ranges fill whole words in their middle
+	c.SetRange(80, 300)
+	for i := 80; i < 300; i++ {
+		expected[i] = true
+	}
+	checkBools(t, "ranged", c, expected)
+}
+
+func TestBoolColumnFromWords(t *testing.T) {
+	c := BoolColumnFromWords([]uint64{^uint64(0), ^uint64(0)}, 70)
+	expected := make([]bool, 70)
+	for i := range expected {
+		expected[i] = true
+	}
+	checkBools(t, "from words", c, expected)
+
+	// Negating must not leak past the end
+	negated := c.Not()
+	checkBools(t, "negated", negated, make([]bool, 70))
+}
+
+// Growth happens in place, so copies sharing a
+// column's storage still see every value
+func TestBoolColumnGrowthShared(t *testing.T) {
+	c := NewBoolColumn()
+	shared := c
+
+	c.PushFalse(1000)
+	c.SetRange(990, 1000)
+
+	if next, found := shared.NextTruthy(0); !found || next != 990 {
+		t.Fatalf("copy lost growth, %v %v", next, found)
+	}
+}
+
+// Pushing false must grow the set, otherwise negating
+// leaves the pushed values false
+func TestBoolColumnPushFalseNot(t *testing.T) {
+	c := NewBoolColumn()
+	c.PushFalse(10)
+	c.Not()
+	if truthy := len(c.TruthyIndices()); truthy != 10 {
+		t.Fatalf("found %v truthy rows after negating, expected 10", truthy)
+	}
+
+	col := NewRLEUInt32Column(len(RLEUInt32TestSlice))
+	col.Push(RLEUInt32TestSlice)
+	miss := col.Equal(1000)
+	miss.Not()
+	if truthy := len(miss.TruthyIndices()); truthy != len(RLEUInt32TestSlice) {
+		t.Fatalf("found %v truthy rows after negating a miss, expected %v",
+			truthy, len(RLEUInt32TestSlice))
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+// A toy card metadata table keyed by name and set
+type CardDB struct {
+	Names FiniteString32Column
+	Sets  FiniteString32Column
+
+	Rarities FiniteString32Column
+	Colors   FiniteString32Column
+	Types    FiniteString32Column
+}
+
+type CardTuple struct {
+	Name, Set string
+
+	Rarity, Color, Type string
+}
+
+func NewCardDB() CardDB {
+	return NewCardDBSharing(NewDictionary(), NewDictionary())
+}
+
+// Create a table encoding names and sets with existing
+// dictionaries, typically those of a PriceDB
+func NewCardDBSharing(names, sets *Dictionary) CardDB {
+	return CardDB{
+		Names:    NewFiniteString32ColumnSharing(names),
+		Sets:     NewFiniteString32ColumnSharing(sets),
+		Rarities: NewFiniteString32Column(),
+		Colors:   NewFiniteString32Column(),
+		Types:    NewFiniteString32Column(),
+	}
+}
+
+func (db *CardDB) Push(values []CardTuple) {
+	names := make([]string, len(values))
+	sets := make([]string, len(values))
+	rarities := make([]string, len(values))
+	colors := make([]string, len(values))
+	types := make([]string, len(values))
+	for i, c := range values {
+		names[i] = c.Name
+		sets[i] = c.Set
+		rarities[i] = c.Rarity
+		colors[i] = c.Color
+		types[i] = c.Type
+	}
+	db.Names.Push(names)
+	db.Sets.Push(sets)
+	db.Rarities.Push(rarities)
+	db.Colors.Push(colors)
+	db.Types.Push(types)
+}
+
+// Determine the number of cards in this table
+func (db *CardDB) Length() int {
+	return db.Names.Length()
+}
+
+// Materialize the CardTuples stored at each of the provided
+// positions, in the order the positions are provided
+//
+// Negative positions, as produced by a left join which found
+// no match, materialize as an empty CardTuple.
+func (db *CardDB) MaterializePositions(positions []int) []CardTuple {
+	tuples := make([]CardTuple, len(positions))
+	for i, p := range positions {
+		if p < 0 {
+			continue
+		}
+
+		tuples[i] = CardTuple{
+			Name:   db.Names.Access(p),
+			Set:    db.Sets.Access(p),
+			Rarity: db.Rarities.Access(p),
+			Color:  db.Colors.Access(p),
+			Type:   db.Types.Access(p),
+		}
+	}
+
+	return tuples
+}
+
+// Materialize all CardTuples that are truthy from
+// the provided BoolColumn
+func (db *CardDB) MaterializeFromBools(b BoolColumn) []CardTuple {
+	return db.MaterializePositions(b.TruthyIndices())
+}
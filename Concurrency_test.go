@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ingest fresh batches while readers query, every reader
+// must see whole batches only
+//
+// Run with -race to check synchronization.
+func TestConcurrentIngestAndQuery(t *testing.T) {
+	db := NewPriceDB()
+	db.Attach(NameTimeSpec)
+
+	const batches, batchSize = 50, 20
+	start := time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for b := 0; b < batches; b++ {
+			batch := make([]PriceTuple, batchSize)
+			for i := range batch {
+				batch[i] = PriceTuple{
+					// New names keep the dictionary growing
+					Name:  fmt.Sprintf("Card %v", i+b),
+					Set:   "Onslaught",
+					Price: uint32(i),
+					Time:  start.Add(time.Duration(b) * time.Hour),
+				}
+			}
+			if err := db.Push(batch); err != nil {
+				t.Errorf("failed to push: %v", err)
+				return
+			}
+		}
+	}()
+
+	errors := make(chan error, 4)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				db.View(func() {
+					length := db.Prices.Length()
+					if length%batchSize != 0 {
+						errors <- fmt.Errorf("saw partial batch, %v rows", length)
+						return
+					}
+
+					query := db.Sets.Equal("Onslaught")
+					if tuples := db.MaterializeFromBools(query); len(tuples) != length {
+						errors <- fmt.Errorf("bad selection, %v != %v", len(tuples), length)
+						return
+					}
+
+					results, err := db.Execute(Query{
+						Where: []Predicate{{Field: FieldName, Op: OpEqual, Value: "Card 3"}},
+					})
+					if err != nil {
+						errors <- err
+						return
+					}
+					for _, tuple := range results {
+						if tuple.Name != "Card 3" {
+							errors <- fmt.Errorf("bad result, %v", tuple)
+							return
+						}
+					}
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errors)
+	for err := range errors {
+		t.Fatal(err)
+	}
+
+	if db.Prices.Length() != batches*batchSize {
+		t.Fatalf("bad final length, %v", db.Prices.Length())
+	}
+}
+
+// Tables sharing dictionaries synchronize through them even
+// though they hold separate locks
+func TestConcurrentSharedDictionary(t *testing.T) {
+	cards := setupSyntheticCardDB()
+	prices := NewPriceDBSharing(cards.Names.Dictionary(), cards.Sets.Dictionary())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			prices.Push([]PriceTuple{{
+				Name: fmt.Sprintf("Card %v", i), Set: "Darksteel",
+				Price: uint32(i), Time: time.Unix(int64(i), 0),
+			}})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			vial := cards.Names.Equal("Aether Vial")
+			if tuples := cards.MaterializeFromBools(vial); len(tuples) != 1 {
+				t.Errorf("bad card lookup, %v", tuples)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if size := cards.Names.Dictionary().Size(); size != 204 {
+		t.Fatalf("bad dictionary size, %v != 204", size)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"time"
+
+	"github.com/willf/bitset"
+)
+
+// Rows removed from a table but still physically present
+// in its columns
+//
+// A single vector is shared by every column of a table so
+// predicates and aggregates on any of them skip the same rows.
+// A nil vector deletes nothing.
+type DeleteVector struct {
+	rows  *bitset.BitSet
+	count int
+}
+
+func NewDeleteVector() *DeleteVector {
+	return &DeleteVector{
+		rows: bitset.New(0),
+	}
+}
+
+// Mark a row as deleted, reporting whether it was live
+func (d *DeleteVector) Delete(index int) bool {
+	if d.rows.Test(uint(index)) {
+		return false
+	}
+	d.rows.Set(uint(index))
+	d.count++
+
+	return true
+}
+
+// Copy the vector so either may be modified independently
+func (d *DeleteVector) clone() *DeleteVector {
+	if d == nil {
+		return nil
+	}
+	return &DeleteVector{
+		rows:  d.rows.Clone(),
+		count: d.count,
+	}
+}
+
+// Determine if a row has been deleted
+func (d *DeleteVector) Deleted(index int) bool {
+	if d == nil {
+		return false
+	}
+	return d.rows.Test(uint(index))
+}
+
+// Determine how many rows have been deleted
+func (d *DeleteVector) Count() int {
+	if d == nil {
+		return 0
+	}
+	return d.count
+}
+
+// Determine how many rows of a range have been deleted
+func (d *DeleteVector) countRange(r RowRange) int {
+	if d.Count() == 0 {
+		return 0
+	}
+
+	count := 0
+	for i, ok := d.rows.NextSet(uint(r.Start)); ok && i < uint(r.End); i, ok = d.rows.NextSet(i + 1) {
+		count++
+	}
+	return count
+}
+
+// Clear every deleted row from a result
+//
+// Deletes are expected to be sparse so this visits
+// only the deleted rows.
+func (d *DeleteVector) mask(results BoolColumn) {
+	if d == nil || d.count == 0 {
+		return
+	}
+	for i, ok := d.rows.NextSet(0); ok && i < results.end; i, ok = d.rows.NextSet(i + 1) {
+		results.Clear(int(i))
+	}
+}
+
+// Record deletions shared by every column of this database
+//
+// Columns built before this point see them immediately.
+func (db *PriceDB) attachDeleteVector(deleted *DeleteVector) {
+	db.deleted = deleted
+	db.Names.contents.deleted = deleted
+	db.Sets.contents.deleted = deleted
+	db.Prices.deleted = deleted
+	db.Times.deleted = deleted
+}
+
+// Select every row of the database which has not been deleted
+func (db *PriceDB) Live() BoolColumn {
+	query := allRows(db.Prices.Length())
+	db.deleted.mask(query)
+
+	return query
+}
+
+// Negate a selection of the database in place and return it,
+// leaving deleted rows unselected
+//
+// BoolColumn's own Not has no knowledge of deletes, so
+// would select every deleted row.
+func (db *PriceDB) Not(b BoolColumn) BoolColumn {
+	b.Not()
+	db.deleted.mask(b)
+
+	return b
+}
+
+// Determine how many rows of the database have not been deleted
+func (db *PriceDB) LiveLength() int {
+	return db.Prices.Length() - db.deleted.Count()
+}
+
+// Delete every selected row, returning how many were live
+//
+// Rows remain in place, so positions are stable, until
+// Compact is called. Deleted keys may be ingested again.
+// Attached projections have the rows removed without
+// a rebuild.
+func (db *PriceDB) Delete(b BoolColumn) int {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	db.thaw()
+
+	deleted := make([]int, 0)
+	for p, ok := b.NextTruthy(0); ok; p, ok = b.NextTruthy(p + 1) {
+		if p >= db.Prices.Length() || !db.deleted.Delete(p) {
+			continue
+		}
+		deleted = append(deleted, p)
+
+		if db.keys != nil {
+			key := db.keyAt(p)
+			if row, found := db.keys.rows[key]; found && row == p {
+				delete(db.keys.rows, key)
+			}
+		}
+	}
+
+	// Projections only mark the rows, leaving
+	// their removal to a merge or Compact
+	for _, tuple := range db.MaterializePositions(deleted) {
+		for _, proj := range db.projections.projections {
+			proj.Remove(tuple)
+		}
+	}
+
+	return len(deleted)
+}
+
+// Physically rewrite every column without its deleted rows
+//
+// Positions of surviving rows shift down, so any BoolColumn
+// or position computed beforehand is invalidated.
+func (db *PriceDB) Compact() {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	if db.deleted.Count() == 0 {
+		return
+	}
+
+	length := db.Prices.Length()
+	live := length - db.deleted.Count()
+
+	names := make([]uint32, 0, live)
+	sets := make([]uint32, 0, live)
+	prices := make([]uint32, 0, live)
+	times := make([]time.Time, 0, live)
+	for i := 0; i < length; i++ {
+		if db.deleted.Deleted(i) {
+			continue
+		}
+		names = append(names, db.Names.AccessCode(i))
+		sets = append(sets, db.Sets.AccessCode(i))
+		prices = append(prices, db.Prices.Access(i))
+		times = append(times, db.Times.Access(i))
+	}
+
+	db.Names.truncate()
+	db.Names.PushCodes(names)
+	db.Sets.truncate()
+	db.Sets.PushCodes(sets)
+	db.Prices = NewUInt32Column()
+	db.Prices.Push(prices)
+	db.Times = NewTimeColumn()
+	db.Times.Push(times)
+
+	db.attachDeleteVector(NewDeleteVector())
+
+	// Nothing is shared with snapshots any longer
+	db.frozen = false
+
+	if db.keys != nil {
+		db.keys.rows = make(map[rowKey]int, live)
+		db.keys.insert(db, 0, live)
+	}
+	db.rebuildProjections()
+}
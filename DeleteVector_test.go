@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+)
+
+// Postgres equivalent
+//  DELETE FROM prices.mtgprice WHERE name = 'Windswept Heath';
+func TestDeleteHidesRows(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(NameTimeSpec)
+	sum := db.Prices.Sum()
+
+	deleted := db.Delete(db.Names.Equal("Windswept Heath"))
+	if deleted != 4 {
+		t.Fatalf("bad deleted count, %v != 4", deleted)
+	}
+	if again := db.Delete(db.Names.Equal("Windswept Heath")); again != 0 {
+		t.Fatalf("deleted rows deleted again, %v", again)
+	}
+
+	// Rows remain in place
+	if db.Prices.Length() != 12 || db.LiveLength() != 8 {
+		t.Fatalf("bad lengths, %v and %v", db.Prices.Length(), db.LiveLength())
+	}
+
+	// Predicates skip deleted rows
+	heath := db.Names.Equal("Windswept Heath")
+	if len(heath.TruthyIndices()) != 0 {
+		t.Fatalf("predicate matched deleted rows")
+	}
+	expensive := db.Prices.More(9000)
+	if len(expensive.TruthyIndices()) != 0 {
+		t.Fatalf("negated predicate matched deleted rows")
+	}
+
+	// Aggregates skip deleted rows
+	if db.Prices.Sum() != sum-(9000+9500+9400+15499) {
+		t.Fatalf("sum included deleted rows")
+	}
+
+	// Materializing ignores deleted rows even when selected
+	if tuples := db.MaterializeFromBools(allRows(12)); len(tuples) != 8 {
+		t.Fatalf("materialized deleted rows, %v", len(tuples))
+	}
+
+	// Queries and projections skip deleted rows
+	results, err := db.Execute(Query{})
+	if err != nil || len(results) != 8 {
+		t.Fatalf("bad query results, %v %v", len(results), err)
+	}
+	if proj.LiveLength() != 8 || proj.MainLength() != 12 {
+		t.Fatalf("bad projection lengths, %v and %v",
+			proj.LiveLength(), proj.MainLength())
+	}
+	if tuples := proj.MaterializeFromBools(allRows(proj.Length())); len(tuples) != 8 {
+		t.Fatalf("projection materialized deleted rows, %v", len(tuples))
+	}
+	nameTime := NameTimeProjection{*proj}
+	latest := nameTime.Latest("Windswept Heath")
+	if len(latest.TruthyIndices()) != 0 || len(nameTime.History("Windswept Heath",
+		keyIndexDay(0), keyIndexDay(10))) != 0 {
+		t.Fatalf("projection lookups found deleted rows")
+	}
+	if _, ok := nameTime.LatestAll()["Windswept Heath"]; ok {
+		t.Fatalf("projection walk found deleted rows")
+	}
+	if equal := proj.NameEqual("Windswept Heath"); len(equal.TruthyIndices()) != 0 {
+		t.Fatalf("projection predicate matched deleted rows")
+	}
+}
+
+func TestDeleteThenCompact(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	if err := db.EnableKeyIndex(DuplicateReject); err != nil {
+		t.Fatalf("failed to enable key index: %v", err)
+	}
+
+	foil := db.Sets.Equal("Avacyn Restored Foil")
+	db.Delete(foil)
+
+	// Deleted keys may be ingested again
+	tuples := syntheticPriceTuples()
+	var reingest []PriceTuple
+	for _, tuple := range tuples {
+		if tuple.Set == "Avacyn Restored Foil" {
+			reingest = append(reingest, tuple)
+		}
+	}
+	if err := db.Push(reingest[:1]); err != nil {
+		t.Fatalf("failed to reingest deleted key: %v", err)
+	}
+
+	db.Compact()
+	if db.Prices.Length() != 11 || db.LiveLength() != 11 {
+		t.Fatalf("bad compacted length, %v", db.Prices.Length())
+	}
+
+	foil = db.Sets.Equal("Avacyn Restored Foil")
+	indices := foil.TruthyIndices()
+	if len(indices) != 1 || indices[0] != 10 {
+		t.Fatalf("bad compacted rows, %v", indices)
+	}
+
+	// Key index follows the new positions
+	row, found := db.Lookup(reingest[0].Name, reingest[0].Set, reingest[0].Time)
+	if !found || row != 10 {
+		t.Fatalf("bad lookup after compaction, %v %v", row, found)
+	}
+	row, found = db.Lookup(tuples[11].Name, tuples[11].Set, tuples[11].Time)
+	if !found || db.Prices.Access(row) != tuples[11].Price {
+		t.Fatalf("bad lookup after compaction, %v %v", row, found)
+	}
+}
+
+func TestDeleteHashJoin(t *testing.T) {
+	prices := setupSyntheticPriceDB()
+	cards := setupSyntheticCardDB()
+
+	prices.Delete(prices.Names.Equal("Griselbrand"))
+
+	inner := HashJoin(&prices, allRows(prices.Prices.Length()),
+		&cards, allRows(cards.Length()), InnerJoin)
+	for _, p := range inner.Left {
+		if prices.Names.Access(p) == "Griselbrand" {
+			t.Fatalf("joined deleted row %v", p)
+		}
+	}
+	if len(inner.Left) != 6 {
+		t.Fatalf("bad join size, %v != 6", len(inner.Left))
+	}
+}
+
+// Removed rows are dropped from a projection by a merge,
+// which happens once enough are removed
+func TestDeleteMergesProjections(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(NameTimeSpec)
+	proj.MergeThreshold = 3
+	snap := db.Snapshot()
+
+	db.Delete(db.Names.Equal("Windswept Heath"))
+	if proj.MainLength() != 9 || proj.LiveLength() != 8 {
+		t.Fatalf("bad projection lengths, %v and %v",
+			proj.MainLength(), proj.LiveLength())
+	}
+
+	// Snapshots keep seeing their rows
+	copied := snap.AttachedProjections()[0]
+	if tuples := copied.MaterializeFromBools(allRows(copied.Length())); len(tuples) != 12 {
+		t.Fatalf("snapshot projection lost rows, %v", len(tuples))
+	}
+
+	proj.Merge()
+	if proj.MainLength() != 8 || proj.LiveLength() != 8 {
+		t.Fatalf("merge kept removed rows, %v", proj.MainLength())
+	}
+	for _, tuple := range proj.MaterializeFromBools(allRows(proj.Length())) {
+		if tuple.Name == "Windswept Heath" {
+			t.Fatalf("merge kept a removed row")
+		}
+	}
+}
+
+// Negating a selection must not bring deleted rows back,
+// with or without a bitmap index
+func TestDeleteNot(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		db := setupSyntheticPriceDB()
+		if indexed {
+			db.BuildIndex(FieldName)
+		}
+		db.Delete(db.Names.Equal("Windswept Heath"))
+		db.Delete(db.Names.Equal("Avacyn, Angel of Hope"))
+
+		others := db.Not(db.Names.Equal("Griselbrand"))
+		if indices := others.TruthyIndices(); len(indices) != 0 {
+			t.Fatalf("indexed %v: negation selected deleted rows %v",
+				indexed, indices)
+		}
+
+		// Planned negations agree
+		results, err := db.Execute(Query{Where: []Predicate{
+			{Field: FieldPrice, Op: OpMore, Value: uint32(0)},
+		}})
+		if err != nil || len(results) != db.LiveLength() {
+			t.Fatalf("indexed %v: bad query results, %v %v",
+				indexed, len(results), err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// Translation and inversion structure for compressing
+// strings into flat ints
+//
+// A dictionary may be shared by any number of columns, across
+// tables and projections, making their codes directly comparable.
+// Sharers retain the dictionary and release it when done, so the
+// owner can tell when codes are no longer relied upon.
+//
+// Tables sharing a dictionary do not share a lock, so the
+// dictionary guards itself.
+type Dictionary struct {
+	lock sync.RWMutex
+
+	translator map[string]uint32
+
+	// Indexed by code, code 0 is never assigned so
+	// missing strings can be represented by it
+	inverter []string
+
+	references int
+}
+
+// Create an empty dictionary
+//
+// The dictionary has no references until a column retains it.
+func NewDictionary() *Dictionary {
+	return &Dictionary{
+		translator: make(map[string]uint32),
+		inverter:   []string{""},
+	}
+}
+
+// Take an additional reference to the dictionary
+func (d *Dictionary) Retain() *Dictionary {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.references++
+	return d
+}
+
+// Drop a reference to the dictionary
+//
+// Once the last reference is dropped the dictionary's
+// contents are discarded. Releasing a reference never
+// taken panics rather than discarding codes a holder
+// may still decode.
+func (d *Dictionary) Release() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.references == 0 {
+		panic("release of an unretained Dictionary")
+	}
+	d.references--
+	if d.references == 0 {
+		d.translator = make(map[string]uint32)
+		d.inverter = []string{""}
+	}
+}
+
+// Determine how many holders the dictionary has
+func (d *Dictionary) References() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.references
+}
+
+// Determine the number of distinct strings held
+func (d *Dictionary) Size() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return len(d.inverter) - 1
+}
+
+// Translate a string, assigning a new code if required
+func (d *Dictionary) Encode(value string) uint32 {
+	// Most strings are already present, so try
+	// without excluding readers first
+	if code, ok := d.Lookup(value); ok {
+		return code
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	code, ok := d.translator[value]
+	if !ok {
+		code = uint32(len(d.inverter))
+
+		d.translator[value] = code
+		d.inverter = append(d.inverter, value)
+	}
+
+	return code
+}
+
+// Translate a string without assigning a new code
+//
+// Strings not present translate to 0, which matches nothing.
+func (d *Dictionary) Lookup(value string) (uint32, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	code, ok := d.translator[value]
+	return code, ok
+}
+
+// Invert a code back into its string
+func (d *Dictionary) Decode(code uint32) string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.inverter[code]
+}
+
+// Determine the position of every code in sorted string order
+//
+// The result is indexed by code, allowing codes to be compared
+// as integers while still ordering as the strings they encode.
+func (d *Dictionary) ranks() []uint32 {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	codes := make([]uint32, 0, len(d.inverter)-1)
+	for code := 1; code < len(d.inverter); code++ {
+		codes = append(codes, uint32(code))
+	}
+	sort.Sort(StringOrderedCodes{codes, d})
+
+	ranks := make([]uint32, len(d.inverter))
+	for rank, code := range codes {
+		ranks[code] = uint32(rank)
+	}
+
+	return ranks
+}
+
+// Codes ordered by the strings they encode
+//
+// The dictionary's lock must be held while sorting.
+type StringOrderedCodes struct {
+	Codes      []uint32
+	dictionary *Dictionary
+}
+
+func (a StringOrderedCodes) Len() int {
+	return len(a.Codes)
+}
+func (a StringOrderedCodes) Swap(i, j int) {
+	a.Codes[i], a.Codes[j] = a.Codes[j], a.Codes[i]
+}
+func (a StringOrderedCodes) Less(i, j int) bool {
+	return a.dictionary.inverter[a.Codes[i]] < a.dictionary.inverter[a.Codes[j]]
+}
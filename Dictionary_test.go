@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+)
+
+// Columns sharing a dictionary agree on codes, in
+// tables and projections alike
+func TestDictionarySharedCodes(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	if proj.Names.Dictionary() != db.Names.Dictionary() {
+		t.Fatalf("projection did not share the database's dictionary")
+	}
+
+	for i := 0; i < proj.Length(); i++ {
+		name := proj.Names.Access(i)
+		code, ok := db.Names.Dictionary().Lookup(name)
+		if !ok || proj.Names.AccessCode(i) != code {
+			t.Fatalf("code for %v differs at %v", name, i)
+		}
+	}
+
+	// A database, its projection and their sets
+	if references := db.Names.Dictionary().References(); references != 2 {
+		t.Fatalf("found %v references, expected 2", references)
+	}
+
+	// Strings added by any sharer are visible to all
+	cards := NewCardDBSharing(db.Names.Dictionary(), db.Sets.Dictionary())
+	cards.Push([]CardTuple{{Name: "Aether Vial", Set: "Darksteel"}})
+	if _, ok := db.Names.Dictionary().Lookup("Aether Vial"); !ok {
+		t.Fatalf("shared dictionary missing a string")
+	}
+	equal := db.Names.Equal("Aether Vial")
+	if len(equal.TruthyIndices()) != 0 {
+		t.Fatalf("found rows for a string only the cards hold")
+	}
+}
+
+// Dictionaries sort their codes as the strings they encode
+func TestDictionaryRanks(t *testing.T) {
+	dictionary := NewDictionary()
+	values := []string{"b", "c", "a", "b"}
+	for _, v := range values {
+		dictionary.Encode(v)
+	}
+
+	if dictionary.Size() != 3 {
+		t.Fatalf("found %v strings, expected 3", dictionary.Size())
+	}
+
+	ranks := dictionary.ranks()
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "c"}} {
+		first, _ := dictionary.Lookup(pair[0])
+		second, _ := dictionary.Lookup(pair[1])
+		if ranks[first] >= ranks[second] {
+			t.Fatalf("%v does not rank before %v", pair[0], pair[1])
+		}
+	}
+}
+
+// Joins between tables sharing dictionaries skip translation
+// and agree with those that translate
+func TestHashJoinSharedDictionaries(t *testing.T) {
+	prices := setupSyntheticPriceDB()
+	separate := setupSyntheticCardDB()
+
+	shared := NewCardDBSharing(prices.Names.Dictionary(), prices.Sets.Dictionary())
+	shared.Push(separate.MaterializeFromBools(allRows(separate.Length())))
+
+	all := allRows(prices.Prices.Length())
+	viaShared := HashJoin(&prices, all, &shared, allRows(shared.Length()), InnerJoin)
+	viaSeparate := HashJoin(&prices, all, &separate, allRows(separate.Length()), InnerJoin)
+
+	if len(viaShared.Left) != len(viaSeparate.Left) {
+		t.Fatalf("shared join found %v rows, separate found %v",
+			len(viaShared.Left), len(viaSeparate.Left))
+	}
+	for i := range viaShared.Left {
+		if viaShared.Left[i] != viaSeparate.Left[i] ||
+			viaShared.Right[i] != viaSeparate.Right[i] {
+			t.Fatalf("joins differ at %v", i)
+		}
+	}
+}
+
+// Snapshots hold their own references, so releasing everything
+// else never discards codes a snapshot still decodes
+func TestDictionarySnapshotReferences(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(NameTimeSpec)
+	dictionary := db.Names.Dictionary()
+
+	snap := db.Snapshot()
+	if references := dictionary.References(); references != 4 {
+		t.Fatalf("found %v references, expected 4", references)
+	}
+
+	db.Names.Release()
+	proj.Names.Release()
+	if name := snap.Names.Access(0); name == "" {
+		t.Fatalf("snapshot lost its dictionary")
+	}
+
+	snap.Release()
+	if references := dictionary.References(); references != 0 {
+		t.Fatalf("found %v references, expected 0", references)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("releasing an unretained dictionary did not panic")
+		}
+	}()
+	dictionary.Release()
+}
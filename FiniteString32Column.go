@@ -1,23 +1,37 @@
 package main
 
+import "github.com/willf/bitset"
+
 type FiniteString32Column struct {
 	// Underlying storage exploits all properties of ints
 	contents UInt32Column
 
-	// Translation and inversion structure
-	// for compressing strings into flat ints
-	translator        map[string]uint32
-	inverter          map[uint32]string
-	translatorCounter uint32
+	// Codes present in each row group
+	present []*presentCodes
+
+	// Rows of each code, nil unless built
+	index *BitmapIndex
+
+	// Possibly shared translation of strings into flat ints
+	dictionary *Dictionary
+
+	// Codes at or past this were assigned after the column was
+	// bounded by a snapshot and are treated as unknown, zero
+	// when unbounded
+	version uint32
 }
 
 func NewFiniteString32Column() FiniteString32Column {
+	return NewFiniteString32ColumnSharing(NewDictionary())
+}
+
+// Create a column encoding values with an existing dictionary,
+// so its codes are comparable with every other column using it
+func NewFiniteString32ColumnSharing(dictionary *Dictionary) FiniteString32Column {
 	return FiniteString32Column{
 		contents: NewUInt32Column(),
 
-		translator:        make(map[string]uint32),
-		inverter:          make(map[uint32]string),
-		translatorCounter: 0,
+		dictionary: dictionary.Retain(),
 	}
 }
 
@@ -25,21 +39,37 @@ func (c *FiniteString32Column) Push(values []string) {
 	translated := make([]uint32, len(values))
 
 	for i, v := range values {
-		key, ok := c.translator[v]
-		if !ok {
-			// Increment translator counter
-			c.translatorCounter += 1
-			key = c.translatorCounter
-
-			// Add key to translator and inverter
-			c.translator[v] = key
-			c.inverter[key] = v
+		translated[i] = c.dictionary.Encode(v)
+	}
+
+	c.PushCodes(translated)
+}
+
+// Push values already encoded by this column's dictionary
+func (c *FiniteString32Column) PushCodes(codes []uint32) {
+	start := c.contents.Length()
+	for i, code := range codes {
+		group := (start + i) >> rowGroupShift
+		if group == len(c.present) {
+			c.present = append(c.present, newPresentCodes())
+		}
+		c.present[group].add(code)
+		if c.index != nil {
+			c.index.add(start+i, code)
 		}
-		translated[i] = key
 	}
 
 	// Push to underlying storage
-	c.contents.Push(translated)
+	c.contents.Push(codes)
+}
+
+// Drop every value, keeping the dictionary
+func (c *FiniteString32Column) truncate() {
+	c.contents = NewUInt32Column()
+	c.present = nil
+	if c.index != nil {
+		c.index = newBitmapIndex()
+	}
 }
 
 // Access the value stored at the named index
@@ -49,9 +79,62 @@ func (c *FiniteString32Column) Push(values []string) {
 func (c *FiniteString32Column) Access(index int) string {
 	// Fetch compact representation
 	raw := c.contents.Access(index)
+	if !c.known(raw) {
+		return ""
+	}
 
 	// Return the readable string
-	return c.inverter[raw]
+	return c.dictionary.Decode(raw)
+}
+
+// Translate a string without assigning a new code, ignoring
+// codes assigned past the column's version
+func (c *FiniteString32Column) lookup(value string) (uint32, bool) {
+	code, found := c.dictionary.Lookup(value)
+	if !found || !c.known(code) {
+		return 0, false
+	}
+	return code, true
+}
+
+// Determine if a code predates the column's version
+func (c *FiniteString32Column) known(code uint32) bool {
+	return c.version == 0 || code < c.version
+}
+
+// Access the code stored at the named index
+func (c *FiniteString32Column) AccessCode(index int) uint32 {
+	return c.contents.Access(index)
+}
+
+// Overwrite the value stored at the named index
+//
+// Values new to the dictionary are assigned a code, growing
+// it for every column sharing it. As with Access, this
+// performs no range checking.
+func (c *FiniteString32Column) Set(index int, value string) {
+	code := c.dictionary.Encode(value)
+	if c.index != nil {
+		c.index.remove(index, c.contents.Access(index))
+		c.index.add(index, code)
+	}
+	c.contents.Set(index, code)
+	c.present[index>>rowGroupShift].add(code)
+}
+
+// Determine the dictionary this column encodes with
+func (c *FiniteString32Column) Dictionary() *Dictionary {
+	return c.dictionary
+}
+
+// Drop this column's reference to its dictionary
+func (c *FiniteString32Column) Release() {
+	c.dictionary.Release()
+}
+
+// Determine the length of this column
+func (c *FiniteString32Column) Length() int {
+	return c.contents.Length()
 }
 
 // Determine all values equal a provided value
@@ -60,30 +143,53 @@ func (c *FiniteString32Column) Equal(value string) BoolColumn {
 
 	// Translate the string into something
 	// our underlying storage can handle
-	translated := c.translator[value]
+	translated, found := c.lookup(value)
+	if c.index != nil {
+		return c.indexEqual(translated, found)
+	}
 
-	return c.contents.Equal(translated)
+	results := scanGroups(c.contents.length, func(group int, words []uint64) {
+		chunk := c.contents.chunks[group]
+		packZone(c.present[group].equal(translated), words, len(chunk), func() {
+			equalWordsUInt32(chunk, translated, words)
+		})
+	})
+	c.contents.deleted.mask(results)
+
+	return results
 }
 
 // Determine all values equal to a member of the provided values
 // and return them positionally as a BoolColumn
 //
-// Cannot handle empty slices, for single values call Equal instead
+// Row groups holding none of the values, or only them, are
+// decided from their present codes without a scan.
 func (c *FiniteString32Column) Within(values []string) BoolColumn {
+	if c.index != nil {
+		return c.indexWithin(values)
+	}
 
-	var query *BoolColumn
+	// Present codes count each distinct code once, so
+	// repeated values must be too
+	members := bitset.New(0)
+	codes := make([]uint32, 0, len(values))
 	for _, v := range values {
-		// Translate the string into something
-		// our underlying storage can handle
-		translated := c.translator[v]
-		result := c.contents.Equal(translated)
-		if query == nil {
-			query = &result
-		} else {
-			result = query.OR(result)
-			query = &result
+		translated, found := c.lookup(v)
+		if found && !members.Test(uint(translated)) {
+			members.Set(uint(translated))
+			codes = append(codes, translated)
 		}
 	}
 
-	return *query
+	results := scanGroups(c.contents.length, func(group int, words []uint64) {
+		chunk := c.contents.chunks[group]
+		packZone(c.present[group].within(codes), words, len(chunk), func() {
+			packWords(words, len(chunk), func(i int) bool {
+				return members.Test(uint(chunk[i]))
+			})
+		})
+	})
+	c.contents.deleted.mask(results)
+
+	return results
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Sum the prices of selected rows grouped by name or set
+//
+// Equivalent to SELECT field, SUM(price) ... GROUP BY field.
+// Each row group is grouped by dictionary code on its own,
+// the partial groups are then merged and decoded once.
+func (db *PriceDB) SumPricesBy(selection BoolColumn,
+	field PriceField) (map[string]uint64, error) {
+
+	var column *FiniteString32Column
+	switch field {
+	case FieldName:
+		column = &db.Names
+	case FieldSet:
+		column = &db.Sets
+	default:
+		return nil, fmt.Errorf("cannot group by '%v'", field)
+	}
+
+	length := db.Prices.Length()
+	partials := make([]map[uint32]uint64, rowGroups(length))
+
+	eachGroup(length, func(group int, r RowRange) {
+		groups := make(map[uint32]uint64)
+		for p, ok := selection.NextTruthy(r.Start); ok && p < r.End; p, ok = selection.NextTruthy(p + 1) {
+			if db.deleted.Deleted(p) {
+				continue
+			}
+			groups[column.AccessCode(p)] += uint64(db.Prices.Access(p))
+		}
+		partials[group] = groups
+	})
+
+	merged := make(map[uint32]uint64)
+	for _, groups := range partials {
+		for code, sum := range groups {
+			merged[code] += sum
+		}
+	}
+
+	sums := make(map[string]uint64, len(merged))
+	dictionary := column.Dictionary()
+	for code, sum := range merged {
+		sums[dictionary.Decode(code)] = sum
+	}
+
+	return sums, nil
+}
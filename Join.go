@@ -0,0 +1,166 @@
+package main
+
+// A table which can be joined on name and set
+//
+// Implemented by both PriceDB and CardDB.
+type JoinTable interface {
+	joinKeys() (names, sets *FiniteString32Column)
+}
+
+func (db *PriceDB) joinKeys() (names, sets *FiniteString32Column) {
+	return &db.Names, &db.Sets
+}
+
+func (db *CardDB) joinKeys() (names, sets *FiniteString32Column) {
+	return &db.Names, &db.Sets
+}
+
+type JoinKind uint32
+
+const (
+	// Every pair of matching rows
+	InnerJoin JoinKind = iota
+	// Every pair of matching rows, plus unmatched left rows
+	LeftJoin
+	// Left rows with at least one match
+	SemiJoin
+	// Left rows with no match
+	AntiJoin
+)
+
+// Positions of the rows a join produced
+//
+// Left and Right are parallel for inner and left joins, Right
+// being -1 where a left join found no match. Semi and anti
+// joins produce only Left.
+type JoinResult struct {
+	Left  []int
+	Right []int
+}
+
+// The left rows of a join as a selection of the left table
+//
+// This lets a filter on one table restrict the other, for
+// example a semi join against rare cards selecting prices.
+func (r JoinResult) LeftSelection() BoolColumn {
+	results := NewBoolColumn()
+	for _, p := range r.Left {
+		results.Set(p)
+	}
+
+	return results
+}
+
+// Join the selected rows of two tables on name and set
+//
+// The right side builds a hash table keyed by its dictionary
+// codes, which the left side probes after translating each of
+// its codes once. Tables sharing dictionaries skip translation.
+// Left rows are produced in storage order and deleted rows
+// on either side are ignored.
+func HashJoin(left JoinTable, leftSel BoolColumn,
+	right JoinTable, rightSel BoolColumn, kind JoinKind) JoinResult {
+
+	rightNames, rightSets := right.joinKeys()
+	leftNames, leftSets := left.joinKeys()
+
+	// Build
+	built := make(map[uint64][]int)
+	for p, ok := rightSel.NextTruthy(0); ok; p, ok = rightSel.NextTruthy(p + 1) {
+		if rightNames.contents.deleted.Deleted(p) {
+			continue
+		}
+		key := joinKey(rightNames.contents.Access(p), rightSets.contents.Access(p))
+		built[key] = append(built[key], p)
+	}
+
+	// Translate left codes to right codes lazily,
+	// each distinct code costing a single lookup
+	nameCodes := newCodeTranslation(leftNames, rightNames)
+	setCodes := newCodeTranslation(leftSets, rightSets)
+
+	// Probe
+	result := JoinResult{Left: make([]int, 0)}
+	if kind == InnerJoin || kind == LeftJoin {
+		result.Right = make([]int, 0)
+	}
+	for p, ok := leftSel.NextTruthy(0); ok; p, ok = leftSel.NextTruthy(p + 1) {
+		if leftNames.contents.deleted.Deleted(p) {
+			continue
+		}
+		var matches []int
+
+		name, nameFound := nameCodes.translate(leftNames.contents.Access(p))
+		set, setFound := setCodes.translate(leftSets.contents.Access(p))
+		if nameFound && setFound {
+			matches = built[joinKey(name, set)]
+		}
+
+		switch kind {
+		case InnerJoin, LeftJoin:
+			for _, m := range matches {
+				result.Left = append(result.Left, p)
+				result.Right = append(result.Right, m)
+			}
+			if len(matches) == 0 && kind == LeftJoin {
+				result.Left = append(result.Left, p)
+				result.Right = append(result.Right, -1)
+			}
+		case SemiJoin:
+			if len(matches) > 0 {
+				result.Left = append(result.Left, p)
+			}
+		case AntiJoin:
+			if len(matches) == 0 {
+				result.Left = append(result.Left, p)
+			}
+		}
+	}
+
+	return result
+}
+
+// Pack a name and set code into a single hash key
+func joinKey(name, set uint32) uint64 {
+	return uint64(name)<<32 | uint64(set)
+}
+
+// A lazily filled mapping from one dictionary's
+// codes to another's
+type codeTranslation struct {
+	from, to *Dictionary
+
+	// Indexed by from code, zero when not yet translated
+	codes []uint32
+	known []bool
+}
+
+func newCodeTranslation(from, to *FiniteString32Column) codeTranslation {
+	translation := codeTranslation{
+		from: from.Dictionary(),
+		to:   to.Dictionary(),
+	}
+
+	// Shared dictionaries need no translation at all
+	if translation.from != translation.to {
+		translation.codes = make([]uint32, translation.from.Size()+1)
+		translation.known = make([]bool, translation.from.Size()+1)
+	}
+
+	return translation
+}
+
+// Translate a code, reporting false when the string
+// it encodes is absent from the other dictionary
+func (t *codeTranslation) translate(code uint32) (uint32, bool) {
+	if t.from == t.to {
+		return code, true
+	}
+
+	if !t.known[code] {
+		t.codes[code], _ = t.to.Lookup(t.from.Decode(code))
+		t.known[code] = true
+	}
+
+	return t.codes[code], t.codes[code] != 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+)
+
+// A small metadata table covering some of the synthetic cards
+//
+// Windswept Heath's foil printing is deliberately absent
+// and a card with no prices is present.
+func setupSyntheticCardDB() CardDB {
+	db := NewCardDB()
+	db.Push([]CardTuple{
+		{Name: "Windswept Heath", Set: "Onslaught",
+			Rarity: "Rare", Color: "Colorless", Type: "Land"},
+		{Name: "Griselbrand", Set: "Avacyn Restored",
+			Rarity: "Mythic", Color: "Black", Type: "Creature"},
+		{Name: "Griselbrand", Set: "Avacyn Restored Foil",
+			Rarity: "Mythic", Color: "Black", Type: "Creature"},
+		{Name: "Avacyn, Angel of Hope", Set: "Avacyn Restored",
+			Rarity: "Mythic", Color: "White", Type: "Creature"},
+		{Name: "Aether Vial", Set: "Darksteel",
+			Rarity: "Uncommon", Color: "Colorless", Type: "Artifact"},
+	})
+
+	return db
+}
+
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice p JOIN cards c
+//  	ON p.name = c.name AND p.set = c.set;
+func TestHashJoinInnerAndLeft(t *testing.T) {
+	prices := setupSyntheticPriceDB()
+	cards := setupSyntheticCardDB()
+
+	all := allRows(prices.Prices.Length())
+	allCards := allRows(cards.Length())
+
+	inner := HashJoin(&prices, all, &cards, allCards, InnerJoin)
+	if len(inner.Left) != 11 || len(inner.Right) != 11 {
+		t.Fatalf("inner join found %v rows, expected 11", len(inner.Left))
+	}
+	priceTuples := prices.MaterializePositions(inner.Left)
+	cardTuples := cards.MaterializePositions(inner.Right)
+	for i := range priceTuples {
+		if priceTuples[i].Name != cardTuples[i].Name ||
+			priceTuples[i].Set != cardTuples[i].Set {
+			t.Fatalf("mismatched pair %v, %v", priceTuples[i], cardTuples[i])
+		}
+	}
+
+	left := HashJoin(&prices, all, &cards, allCards, LeftJoin)
+	if len(left.Left) != 12 {
+		t.Fatalf("left join found %v rows, expected 12", len(left.Left))
+	}
+	unmatched := 0
+	for _, r := range left.Right {
+		if r < 0 {
+			unmatched++
+		}
+	}
+	if unmatched != 1 {
+		t.Fatalf("left join found %v unmatched rows, expected 1", unmatched)
+	}
+}
+
+// Filter prices by a predicate on card metadata, and
+// cards by whether they have prices at all
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice p WHERE EXISTS (SELECT 1 FROM cards c
+//  	WHERE c.name = p.name AND c.set = p.set AND c.rarity = 'Mythic');
+func TestHashJoinSemiAndAnti(t *testing.T) {
+	prices := setupSyntheticPriceDB()
+	cards := setupSyntheticCardDB()
+
+	mythics := cards.Rarities.Equal("Mythic")
+	semi := HashJoin(&prices, allRows(prices.Prices.Length()),
+		&cards, mythics, SemiJoin)
+	selection := semi.LeftSelection()
+	tuples := prices.MaterializeFromBools(selection)
+	if len(tuples) != 8 {
+		t.Fatalf("found %v mythic prices, expected 8", len(tuples))
+	}
+	for _, tuple := range tuples {
+		if tuple.Name == "Windswept Heath" {
+			t.Fatalf("non mythic price selected %v", tuple)
+		}
+	}
+
+	// Cards with no prices, joining the other way around
+	anti := HashJoin(&cards, allRows(cards.Length()),
+		&prices, allRows(prices.Prices.Length()), AntiJoin)
+	unpriced := cards.MaterializePositions(anti.Left)
+	if len(unpriced) != 1 || unpriced[0].Name != "Aether Vial" {
+		t.Fatalf("unexpected unpriced cards %v", unpriced)
+	}
+}
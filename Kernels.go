@@ -0,0 +1,103 @@
+package main
+
+// Comparison and aggregation kernels over plain uint32 values
+//
+// Each kernel has a portable implementation here. Architectures
+// with vectorized versions, see Kernels_amd64.s, supply their own
+// lessWords, equalWords and sumWords, everyone else falls back
+// to the portable ones in Kernels_generic.go.
+//
+// The word kernels handle 64 values per output word, so the
+// exported wrappers hand them whole words of values and finish
+// any remainder here.
+
+// Pack whether each value is less than a provided value
+// into words, 64 values to a word
+func lessWordsUInt32(values []uint32, value uint32, words []uint64) {
+	n := len(values) &^ 63
+	if n > 0 {
+		lessWords(values[:n], value, words)
+	}
+
+	rest := values[n:]
+	packWords(words[n/64:], len(rest), func(i int) bool {
+		return rest[i] < value
+	})
+}
+
+// Pack whether each value is at least a provided value
+// into words, 64 values to a word
+func moreWordsUInt32(values []uint32, value uint32, words []uint64) {
+	n := len(values) &^ 63
+	if n > 0 {
+		lessWords(values[:n], value, words)
+		for w := range words[:n/64] {
+			words[w] = ^words[w]
+		}
+	}
+
+	rest := values[n:]
+	packWords(words[n/64:], len(rest), func(i int) bool {
+		return rest[i] >= value
+	})
+}
+
+// Pack whether each value equals a provided value
+// into words, 64 values to a word
+func equalWordsUInt32(values []uint32, value uint32, words []uint64) {
+	n := len(values) &^ 63
+	if n > 0 {
+		equalWords(values[:n], value, words)
+	}
+
+	rest := values[n:]
+	packWords(words[n/64:], len(rest), func(i int) bool {
+		return rest[i] == value
+	})
+}
+
+// Sum every value
+func sumUInt32(values []uint32) uint64 {
+	n := len(values) &^ 63
+
+	var result uint64
+	if n > 0 {
+		result = sumWords(values[:n])
+	}
+	for _, v := range values[n:] {
+		result = result + uint64(v)
+	}
+
+	return result
+}
+
+// Portable kernels, len(values) must be a multiple of 64
+func lessWordsGeneric(values []uint32, value uint32, words []uint64) {
+	for w := range words[:len(values)/64] {
+		var bits uint64
+		for b, v := range values[w*64 : w*64+64] {
+			if v < value {
+				bits |= 1 << uint(b)
+			}
+		}
+		words[w] = bits
+	}
+}
+func equalWordsGeneric(values []uint32, value uint32, words []uint64) {
+	for w := range words[:len(values)/64] {
+		var bits uint64
+		for b, v := range values[w*64 : w*64+64] {
+			if v == value {
+				bits |= 1 << uint(b)
+			}
+		}
+		words[w] = bits
+	}
+}
+func sumWordsGeneric(values []uint32) uint64 {
+	var result uint64
+	for _, v := range values {
+		result = result + uint64(v)
+	}
+	return result
+}
@@ -0,0 +1,21 @@
+//go:build amd64
+// +build amd64
+
+package main
+
+// Whether the comparison kernels are vectorized
+//
+// Every amd64 processor has SSE2, which is all they use.
+const VectorizedKernels = true
+
+// Implemented in Kernels_amd64.s, len(values) must be a
+// multiple of 64 and words must hold len(values)/64 words
+
+//go:noescape
+func lessWords(values []uint32, value uint32, words []uint64)
+
+//go:noescape
+func equalWords(values []uint32, value uint32, words []uint64)
+
+//go:noescape
+func sumWords(values []uint32) uint64
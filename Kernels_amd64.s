@@ -0,0 +1,114 @@
+//go:build amd64
+// +build amd64
+
+#include "textflag.h"
+
+// func lessWords(values []uint32, value uint32, words []uint64)
+//
+// SSE2 has only signed comparisons, so both sides are biased
+// by 1<<31 to compare as unsigned. Each 4 lane comparison
+// yields 4 bits through MOVMSKPS, 16 of which fill a word.
+TEXT ·lessWords(SB), NOSPLIT, $0-56
+	MOVQ values_base+0(FP), SI
+	MOVQ values_len+8(FP), R8
+	MOVQ words_base+32(FP), DI
+	SHRQ $6, R8
+	JZ   lessDone
+
+	MOVL    $0x80000000, AX
+	MOVQ    AX, X0
+	PSHUFL  $0, X0, X0
+	MOVL    value+24(FP), AX
+	XORL    $0x80000000, AX
+	MOVQ    AX, X1
+	PSHUFL  $0, X1, X1
+
+lessWord:
+	XORQ DX, DX
+	XORQ CX, CX
+
+lessLanes:
+	MOVOU    (SI), X2
+	PXOR     X0, X2
+	MOVO     X1, X3
+	PCMPGTL  X2, X3
+	MOVMSKPS X3, AX
+	SHLQ     CX, AX
+	ORQ      AX, DX
+	ADDQ     $16, SI
+	ADDQ     $4, CX
+	CMPQ     CX, $64
+	JB       lessLanes
+
+	MOVQ DX, (DI)
+	ADDQ $8, DI
+	DECQ R8
+	JNZ  lessWord
+
+lessDone:
+	RET
+
+// func equalWords(values []uint32, value uint32, words []uint64)
+TEXT ·equalWords(SB), NOSPLIT, $0-56
+	MOVQ values_base+0(FP), SI
+	MOVQ values_len+8(FP), R8
+	MOVQ words_base+32(FP), DI
+	SHRQ $6, R8
+	JZ   equalDone
+
+	MOVL   value+24(FP), AX
+	MOVQ   AX, X1
+	PSHUFL $0, X1, X1
+
+equalWord:
+	XORQ DX, DX
+	XORQ CX, CX
+
+equalLanes:
+	MOVOU    (SI), X2
+	PCMPEQL  X1, X2
+	MOVMSKPS X2, AX
+	SHLQ     CX, AX
+	ORQ      AX, DX
+	ADDQ     $16, SI
+	ADDQ     $4, CX
+	CMPQ     CX, $64
+	JB       equalLanes
+
+	MOVQ DX, (DI)
+	ADDQ $8, DI
+	DECQ R8
+	JNZ  equalWord
+
+equalDone:
+	RET
+
+// func sumWords(values []uint32) uint64
+//
+// Values are widened to 64 bits by interleaving with zero,
+// then accumulated two lanes at a time.
+TEXT ·sumWords(SB), NOSPLIT, $0-32
+	MOVQ values_base+0(FP), SI
+	MOVQ values_len+8(FP), CX
+	PXOR X0, X0
+	PXOR X7, X7
+	SHRQ $2, CX
+	JZ   sumDone
+
+sumLanes:
+	MOVOU     (SI), X1
+	MOVO      X1, X2
+	PUNPCKLLQ X7, X1
+	PUNPCKHLQ X7, X2
+	PADDQ     X1, X0
+	PADDQ     X2, X0
+	ADDQ      $16, SI
+	DECQ      CX
+	JNZ       sumLanes
+
+sumDone:
+	PSHUFL $0xEE, X0, X1
+	PADDQ  X1, X0
+	MOVQ   X0, AX
+	MOVQ   AX, ret+24(FP)
+	RET
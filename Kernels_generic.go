@@ -0,0 +1,19 @@
+//go:build !amd64
+// +build !amd64
+
+package main
+
+// Whether the comparison kernels are vectorized
+const VectorizedKernels = false
+
+func lessWords(values []uint32, value uint32, words []uint64) {
+	lessWordsGeneric(values, value, words)
+}
+
+func equalWords(values []uint32, value uint32, words []uint64) {
+	equalWordsGeneric(values, value, words)
+}
+
+func sumWords(values []uint32) uint64 {
+	return sumWordsGeneric(values)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+)
+
+// Values straddling the signed boundary the vectorized
+// kernels bias around
+func kernelTestValues(n int) []uint32 {
+	edges := []uint32{0, 1, 1<<31 - 1, 1 << 31, 1<<31 + 1, 1<<32 - 1}
+
+	values := make([]uint32, n)
+	for i := range values {
+		if i%3 == 0 {
+			values[i] = edges[i%len(edges)]
+		} else {
+			values[i] = uint32(i) * 2654435761
+		}
+	}
+	return values
+}
+
+func TestKernelsMatchGeneric(t *testing.T) {
+	values := kernelTestValues(64*20 + 17)
+	n := len(values) &^ 63
+	probes := []uint32{0, 1, 1 << 31, 1<<31 + 1, 1<<32 - 1, values[5]}
+
+	for _, probe := range probes {
+		vectorized := make([]uint64, n/64)
+		generic := make([]uint64, n/64)
+
+		lessWords(values[:n], probe, vectorized)
+		lessWordsGeneric(values[:n], probe, generic)
+		for w := range generic {
+			if vectorized[w] != generic[w] {
+				t.Fatalf("less %v: word %v differs, %x != %x",
+					probe, w, vectorized[w], generic[w])
+			}
+		}
+
+		equalWords(values[:n], probe, vectorized)
+		equalWordsGeneric(values[:n], probe, generic)
+		for w := range generic {
+			if vectorized[w] != generic[w] {
+				t.Fatalf("equal %v: word %v differs, %x != %x",
+					probe, w, vectorized[w], generic[w])
+			}
+		}
+	}
+
+	if sumWords(values[:n]) != sumWordsGeneric(values[:n]) {
+		t.Fatalf("sum differs, %v != %v",
+			sumWords(values[:n]), sumWordsGeneric(values[:n]))
+	}
+}
+
+// The wrappers finish partial words themselves
+func TestKernelRemainders(t *testing.T) {
+	values := kernelTestValues(64*3 + 17)
+	words := make([]uint64, 4)
+	probe := uint32(1 << 31)
+
+	check := func(name string, expect func(v uint32) bool) {
+		for i, v := range values {
+			if (words[i/64]&(1<<uint(i%64)) != 0) != expect(v) {
+				t.Fatalf("%v: wrong result at %v", name, i)
+			}
+		}
+		if words[3]>>17 != 0 {
+			t.Fatalf("%v: set bits past the end", name)
+		}
+	}
+
+	lessWordsUInt32(values, probe, words)
+	check("less", func(v uint32) bool { return v < probe })
+	moreWordsUInt32(values, probe, words)
+	check("more", func(v uint32) bool { return v >= probe })
+	equalWordsUInt32(values, values[4], words)
+	check("equal", func(v uint32) bool { return v == values[4] })
+
+	var sum uint64
+	for _, v := range values {
+		sum += uint64(v)
+	}
+	if sumUInt32(values) != sum {
+		t.Fatalf("bad sum, %v != %v", sumUInt32(values), sum)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// What ingest does with a row whose key already exists
+type DuplicatePolicy uint32
+
+const (
+	// Refuse the whole batch
+	DuplicateReject DuplicatePolicy = iota
+	// Drop the incoming row
+	DuplicateSkip
+	// Replace the stored row's price with the incoming one
+	DuplicateOverwrite
+)
+
+// The unique key of a price, as dictionary codes and
+// nanoseconds since epoch
+type rowKey struct {
+	name, set uint32
+	nanos     int64
+}
+
+// The unique key of a price, as strings, for rows which
+// may not have been encoded yet
+type tupleKey struct {
+	name, set string
+	nanos     int64
+}
+
+// An index over the (Names, Sets, Times) of a PriceDB,
+// mirroring UNIQUE (name, set, time)
+type KeyIndex struct {
+	Policy DuplicatePolicy
+
+	rows map[rowKey]int
+}
+
+// Build a key index over the database and enforce it on
+// every later Push
+//
+// Fails, leaving the database unindexed, if the
+// database already holds duplicate keys.
+func (db *PriceDB) EnableKeyIndex(policy DuplicatePolicy) error {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	index := &KeyIndex{
+		Policy: policy,
+		rows:   make(map[rowKey]int),
+	}
+
+	length := db.Prices.Length()
+	for i := 0; i < length; i++ {
+		if db.deleted.Deleted(i) {
+			continue
+		}
+		key := db.keyAt(i)
+		if existing, ok := index.rows[key]; ok {
+			return fmt.Errorf("duplicate key at rows %v and %v", existing, i)
+		}
+		index.rows[key] = i
+	}
+
+	db.keys = index
+	return nil
+}
+
+// Stop enforcing and maintaining the key index
+func (db *PriceDB) DisableKeyIndex() {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	db.keys = nil
+}
+
+// Determine the key stored at the named row
+func (db *PriceDB) keyAt(index int) rowKey {
+	return rowKey{
+		name:  db.Names.AccessCode(index),
+		set:   db.Sets.AccessCode(index),
+		nanos: db.Times.Access(index).UnixNano(),
+	}
+}
+
+// Determine the key of a tuple, reporting false if
+// its strings have never been encoded
+func (db *PriceDB) keyOf(name, set string, when time.Time) (rowKey, bool) {
+	nameCode, nameFound := db.Names.Dictionary().Lookup(name)
+	setCode, setFound := db.Sets.Dictionary().Lookup(set)
+
+	key := rowKey{
+		name:  nameCode,
+		set:   setCode,
+		nanos: when.UnixNano(),
+	}
+	return key, nameFound && setFound
+}
+
+// Find the row holding a key
+//
+// With a key index this is a single map lookup, otherwise
+// every row is compared.
+func (db *PriceDB) Lookup(name, set string, when time.Time) (int, bool) {
+	key, ok := db.keyOf(name, set, when)
+	if !ok {
+		return 0, false
+	}
+
+	if db.keys != nil {
+		row, found := db.keys.rows[key]
+		return row, found
+	}
+
+	length := db.Prices.Length()
+	for i := 0; i < length; i++ {
+		if db.keyAt(i) == key && !db.deleted.Deleted(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Determine all rows matching a full key and return
+// them positionally as a BoolColumn
+//
+// Without a key index duplicates are possible, so
+// every match is returned.
+func (db *PriceDB) EqualTuple(name, set string, when time.Time) BoolColumn {
+	if db.keys != nil {
+		results := NewBoolColumn()
+		results.PushFalse(db.Prices.Length())
+		if row, found := db.Lookup(name, set, when); found {
+			results.Set(row)
+		}
+		return results
+	}
+
+	query := db.Names.Equal(name)
+	query.AND(db.Sets.Equal(set))
+	db.Times.ANDAfter(when.Add(-time.Nanosecond), query)
+	db.Times.ANDBefore(when.Add(time.Nanosecond), query)
+
+	return query
+}
+
+// Apply the duplicate policy to an incoming batch, returning
+// the tuples which should be appended and every change made
+// to stored rows
+func (index *KeyIndex) admit(db *PriceDB,
+	values []PriceTuple) ([]PriceTuple, []rowChange, error) {
+	admitted := make([]PriceTuple, 0, len(values))
+	changes := make([]rowChange, 0)
+
+	// Positions within admitted of keys new to this batch
+	batch := make(map[tupleKey]int)
+
+	for _, t := range values {
+		incoming := tupleKey{t.Name, t.Set, t.Time.UnixNano()}
+
+		row, stored := -1, false
+		if key, ok := db.keyOf(t.Name, t.Set, t.Time); ok {
+			row, stored = index.rows[key]
+		}
+		earlier, batched := batch[incoming]
+
+		if !stored && !batched {
+			batch[incoming] = len(admitted)
+			admitted = append(admitted, t)
+			continue
+		}
+
+		switch index.Policy {
+		case DuplicateReject:
+			return nil, nil, fmt.Errorf("duplicate key (%v, %v, %v)",
+				t.Name, t.Set, t.Time)
+		case DuplicateOverwrite:
+			if stored {
+				db.thaw()
+				before := db.MaterializePositions([]int{row})[0]
+				db.Prices.Set(row, t.Price)
+				after := before
+				after.Price = t.Price
+				changes = append(changes, rowChange{before, after})
+			} else {
+				admitted[earlier] = t
+			}
+		}
+	}
+
+	return admitted, changes, nil
+}
+
+// Index rows [start, start+count) which were just appended
+func (index *KeyIndex) insert(db *PriceDB, start, count int) {
+	for i := start; i < start+count; i++ {
+		index.rows[db.keyAt(i)] = i
+	}
+}
+
+// Rebuild every attached projection from the database
+//
+// Only needed once the database has been rewritten, other
+// changes are applied to projections row by row.
+func (db *PriceDB) rebuildProjections() {
+	for _, proj := range db.projections.projections {
+		threshold := proj.MergeThreshold
+		proj.Names.Release()
+		proj.Sets.Release()
+		*proj = ProjectionFromPriceDB(*db, proj.Spec)
+		proj.MergeThreshold = threshold
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func keyIndexDay(d int) time.Time {
+	return time.Date(2016, time.March, d, 3, 51, 45, 0, time.UTC)
+}
+
+func TestKeyIndexLookup(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	// Lookups work the same with and without the index
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			if err := db.EnableKeyIndex(DuplicateReject); err != nil {
+				t.Fatalf("failed to enable key index: %v", err)
+			}
+		}
+
+		row, found := db.Lookup("Windswept Heath", "Onslaught", keyIndexDay(2))
+		if !found {
+			t.Fatalf("indexed=%v: key not found", indexed)
+		}
+		if price := db.Prices.Access(row); price != 9500 {
+			t.Fatalf("indexed=%v: bad price, %v != 9500", indexed, price)
+		}
+
+		_, found = db.Lookup("Windswept Heath", "Onslaught Foil", keyIndexDay(1))
+		if found {
+			t.Fatalf("indexed=%v: found absent key", indexed)
+		}
+		_, found = db.Lookup("Aether Vial", "Darksteel", keyIndexDay(1))
+		if found {
+			t.Fatalf("indexed=%v: found never encoded key", indexed)
+		}
+
+		results := db.EqualTuple("Griselbrand", "Avacyn Restored", keyIndexDay(3))
+		indices := results.TruthyIndices()
+		if len(indices) != 1 || db.Prices.Access(indices[0]) != 2200 {
+			t.Fatalf("indexed=%v: bad EqualTuple, %v", indexed, indices)
+		}
+	}
+}
+
+func TestKeyIndexRejectsExistingDuplicates(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	db.Push(syntheticPriceTuples()[:1])
+
+	if err := db.EnableKeyIndex(DuplicateReject); err == nil {
+		t.Fatalf("enabled key index over duplicate rows")
+	}
+	if results := db.EqualTuple("Griselbrand", "Avacyn Restored",
+		keyIndexDay(1)); len(results.TruthyIndices()) != 2 {
+		t.Fatalf("EqualTuple missed duplicates")
+	}
+}
+
+func TestKeyIndexPolicies(t *testing.T) {
+	duplicate := PriceTuple{Name: "Griselbrand", Set: "Avacyn Restored",
+		Price: 1, Time: keyIndexDay(1)}
+	fresh := PriceTuple{Name: "Griselbrand", Set: "Avacyn Restored",
+		Price: 2300, Time: keyIndexDay(4)}
+
+	cases := []struct {
+		policy DuplicatePolicy
+		err    bool
+		length int
+		price  uint32
+	}{
+		{DuplicateReject, true, 12, 2100},
+		{DuplicateSkip, false, 13, 2100},
+		{DuplicateOverwrite, false, 13, 1},
+	}
+
+	for _, c := range cases {
+		db := setupSyntheticPriceDB()
+		if err := db.EnableKeyIndex(c.policy); err != nil {
+			t.Fatalf("failed to enable key index: %v", err)
+		}
+
+		err := db.Push([]PriceTuple{fresh, duplicate})
+		if (err != nil) != c.err {
+			t.Fatalf("policy %v: unexpected error state, %v", c.policy, err)
+		}
+		if db.Prices.Length() != c.length {
+			t.Fatalf("policy %v: bad length, %v != %v",
+				c.policy, db.Prices.Length(), c.length)
+		}
+
+		row, _ := db.Lookup("Griselbrand", "Avacyn Restored", keyIndexDay(1))
+		if price := db.Prices.Access(row); price != c.price {
+			t.Fatalf("policy %v: bad price, %v != %v", c.policy, price, c.price)
+		}
+	}
+}
+
+func TestKeyIndexDuplicatesWithinBatch(t *testing.T) {
+	db := NewPriceDB()
+	if err := db.EnableKeyIndex(DuplicateOverwrite); err != nil {
+		t.Fatalf("failed to enable key index: %v", err)
+	}
+
+	first := PriceTuple{Name: "Griselbrand", Set: "Avacyn Restored",
+		Price: 2100, Time: keyIndexDay(1)}
+	second := first
+	second.Price = 2150
+
+	if err := db.Push([]PriceTuple{first, second}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	if db.Prices.Length() != 1 || db.Prices.Access(0) != 2150 {
+		t.Fatalf("batch duplicate not overwritten")
+	}
+}
+
+func TestKeyIndexOverwriteRefreshesProjections(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(NameTimeSpec)
+	if err := db.EnableKeyIndex(DuplicateOverwrite); err != nil {
+		t.Fatalf("failed to enable key index: %v", err)
+	}
+
+	err := db.Push([]PriceTuple{{Name: "Griselbrand", Set: "Avacyn Restored",
+		Price: 2250, Time: keyIndexDay(3)}})
+	if err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	// The replaced row is only marked as removed
+	live := proj.MaterializeFromBools(allRows(proj.Length()))
+	if len(live) != 12 {
+		t.Fatalf("bad projection length, %v != 12", len(live))
+	}
+	found := false
+	for _, tuple := range live {
+		if tuple.Set == "Avacyn Restored" && tuple.Name == "Griselbrand" &&
+			tuple.Time.Equal(keyIndexDay(3)) {
+			found = tuple.Price == 2250
+		}
+	}
+	if !found {
+		t.Fatalf("projection missed overwrite")
+	}
+}
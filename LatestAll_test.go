@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+// The latest group of every card, found in one pass, must
+// agree with asking for each card's Latest in turn
+func TestNameTimeProjectionLatestAll(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	// Spread rows across both stores, including a card
+	// which only exists in the delta
+	db := NewPriceDB()
+	db.Push(tuples[:8])
+	proj := NameTimeProjectionFromPriceDB(db)
+	extra := PriceTuple{Name: "Aether Vial", Set: "Darksteel",
+		Price: 4000, Time: tuples[8].Time}
+	proj.Append(append(tuples[8:], extra))
+
+	all := proj.LatestAll()
+	if len(all) != 4 {
+		t.Fatalf("found latest prices for %v cards, expected 4", len(all))
+	}
+
+	for name, group := range all {
+		expected := proj.MaterializeFromBools(proj.Latest(name))
+		if len(group) != len(expected) {
+			t.Fatalf("%v: latest all found %v, latest found %v",
+				name, group, expected)
+		}
+		for i := range expected {
+			if group[i] != expected[i] {
+				t.Fatalf("%v: latest all found %v, latest found %v",
+					name, group, expected)
+			}
+		}
+	}
+}
+
+// The latest price of a card in each set, even when a
+// set was last scraped before the card's final group
+func TestNameTimeProjectionLatestAllBySet(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	bySet := proj.LatestAllBySet()
+	if len(bySet) != 5 {
+		t.Fatalf("found %v name and set pairs, expected 5", len(bySet))
+	}
+
+	foil := bySet[NameSet{"Griselbrand", "Avacyn Restored Foil"}]
+	if foil.Price != 5523 {
+		t.Fatalf("unexpected latest foil price %v", foil)
+	}
+	normal := bySet[NameSet{"Griselbrand", "Avacyn Restored"}]
+	if normal.Price != 2200 {
+		t.Fatalf("unexpected latest price %v", normal)
+	}
+}
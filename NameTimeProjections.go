@@ -7,154 +7,350 @@ import (
 
 // Define a projection sorted first by name
 // then by time
+//
+// Names are run length encoded as a consequence of
+// being the leading sort key.
 type NameTimeProjection struct {
-	Names RLEFiniteString32Column
-	Sets  FiniteString32Column
-
-	Prices UInt32Column
-
-	Times TimeColumn
+	Projection
 }
 
 // Generate a NameTimeProjection from a fully
 // filled PriceDB.
 //
-// I'm not handling updates so this is fine... in theory.
+// Later ingests are handled by Append rather than rebuilding.
 func NameTimeProjectionFromPriceDB(db PriceDB) NameTimeProjection {
-	proj := NameTimeProjection{
-		Names:  NewRLEFiniteString32Column(),
-		Sets:   NewFiniteString32Column(),
-		Prices: NewUInt32Column(),
-		Times:  NewTimeColumn(),
-	}
-
-	// Determine full length of database
-	length := db.Prices.Length()
-
-	// Fetch fully materialized tuples
-	//
-	// TODO: avoid full materialization of the entire
-	// freaking dataset...
-	tuples := make([]PriceTuple, length)
-	for i := 0; i < length; i++ {
-		tuple := PriceTuple{
-			Name:  db.Names.Access(i),
-			Set:   db.Sets.Access(i),
-			Price: db.Prices.Access(i),
-			Time:  db.Times.Access(i),
+	return NameTimeProjection{ProjectionFromPriceDB(db, NameTimeSpec)}
+}
+
+// Query for the latest group of prices in the column for a card
+//
+// Both the card's rows and its latest group are found by binary
+// search, so this costs O(log n) plus the size of the group.
+func (proj *NameTimeProjection) Latest(name string) BoolColumn {
+	start, end := proj.KeyRange(name)
+	deltaStart, deltaEnd := proj.DeltaKeyRange(name)
+
+	// The last row of each range holds its latest time
+	// thanks to our sort invariant
+	var latestTime time.Time
+	found := false
+	if last, ok := proj.lastLive(start, end); ok {
+		latestTime = proj.Times.Access(last)
+		found = true
+	}
+	if deltaStart < deltaEnd {
+		deltaLatest := proj.delta[deltaEnd-1].Time
+		if !found || deltaLatest.After(latestTime) {
+			latestTime = deltaLatest
+			found = true
 		}
+	}
 
-		tuples[i] = tuple
+	query := NewBoolColumn()
+	if !found {
+		return query
 	}
 
-	// Sort the tuples according to Name then time
-	sort.Sort(NameTimeOrderedTuples(tuples))
+	// Offset latestTime by a small amount so we can
+	// include anything in the same scrape
+	from := latestTime.Add(-time.Minute).Add(time.Nanosecond)
+	to := latestTime.Add(time.Nanosecond)
 
-	proj.Push(tuples)
+	first, last := proj.TimeRange(start, end, from, to)
+	query.SetRange(first, last)
+	proj.removed.mask(query)
 
-	return proj
-}
+	main := proj.MainLength()
+	first, last = proj.DeltaTimeRange(deltaStart, deltaEnd, from, to)
+	query.SetRange(main+first, main+last)
 
-func (proj *NameTimeProjection) Push(values []PriceTuple) {
-	names := make([]string, len(values))
-	sets := make([]string, len(values))
-	prices := make([]uint32, len(values))
-	times := make([]time.Time, len(values))
-	for i, p := range values {
-		names[i] = p.Name
-		sets[i] = p.Set
-		prices[i] = p.Price
-		times[i] = p.Time
-	}
-	proj.Names.Push(names)
-	proj.Sets.Push(sets)
-	proj.Prices.Push(prices)
-	proj.Times.Push(times)
+	return query
 }
 
-// Materialize all PriceTuples that are truthy from
-// the provided BoolColumn
-//
-// The assumption is that the provided BoolColumn is
-// the result of a predicate executed on this database.
-// As a result, we do no range checking.
+// Query for every price of a card with a time in [from, to)
+// in ascending order of time
 //
-// Passing a BoolColumn that was not created by this
-// projection instance has no guarantees regarding safety.
-func (proj *NameTimeProjection) MaterializeFromBools(b BoolColumn) []PriceTuple {
-
-	// Grab all indices which this column is truthy
-	//
-	// This is efficient on selective queries but terrible
-	// against sparse queries where a list of FalseIndices
-	// would work better to blacklist against. Oh well.
-	positions := b.TruthyIndices()
-
-	// Keep columns separate for as long as possible
-	names := make([]string, len(positions))
-	sets := make([]string, len(positions))
-	prices := make([]uint32, len(positions))
-	times := make([]time.Time, len(positions))
-	for i, p := range positions {
-		names[i] = proj.Names.Access(p)
-		sets[i] = proj.Sets.Access(p)
-		prices[i] = proj.Prices.Access(p)
-		times[i] = proj.Times.Access(p)
+// As with Latest, this costs O(log n) plus the size of the result.
+func (proj *NameTimeProjection) History(name string, from, to time.Time) []PriceTuple {
+	start, end := proj.KeyRange(name)
+	start, end = proj.TimeRange(start, end, from, to)
+
+	tuples := make([]PriceTuple, 0, end-start)
+	for i := start; i < end; i++ {
+		if !proj.removed.Deleted(i) {
+			tuples = append(tuples, proj.Access(i))
+		}
 	}
 
-	// Stitch tuples back together into fancy structs
+	deltaStart, deltaEnd := proj.DeltaKeyRange(name)
+	deltaStart, deltaEnd = proj.DeltaTimeRange(deltaStart, deltaEnd, from, to)
+	if deltaStart == deltaEnd {
+		return tuples
+	}
+
+	tuples = append(tuples, proj.delta[deltaStart:deltaEnd]...)
+	sortTuples(tuples, []SortKey{{Field: FieldTime}})
+
+	return tuples
+}
+
+// Query for every price of a card, regardless of time,
+// in ascending order of time
+func (proj *NameTimeProjection) FullHistory(name string) []PriceTuple {
+	start, end := proj.KeyRange(name)
+	deltaStart, deltaEnd := proj.DeltaKeyRange(name)
+
+	positions := proj.mergeByTime(make([]int, 0, end-start+deltaEnd-deltaStart),
+		start, end, deltaStart, deltaEnd)
+
 	tuples := make([]PriceTuple, len(positions))
-	for i := range positions {
-		tuples[i] = PriceTuple{
-			Name:  names[i],
-			Set:   sets[i],
-			Price: prices[i],
-			Time:  times[i],
-		}
+	for i, p := range positions {
+		tuples[i] = proj.Access(p)
 	}
 
 	return tuples
+}
 
+// A card printed in a particular set
+type NameSet struct {
+	Name, Set string
 }
 
-// Query for the latest group of prices in the column for a card
-func (proj *NameTimeProjection) Latest(name string) BoolColumn {
-	// Determine latest names and their indices
-	query := proj.Names.Equal(name)
-	truthy := query.TruthyIndices()
+// Append the positions of main rows [start, end) and delta
+// rows [deltaStart, deltaEnd) in ascending order of time
+//
+// Both ranges must already be in time order. Removed
+// rows are skipped.
+func (proj *NameTimeProjection) mergeByTime(positions []int,
+	start, end, deltaStart, deltaEnd int) []int {
+
+	main := proj.MainLength()
+	i, j := start, deltaStart
+	for {
+		for i < end && proj.removed.Deleted(i) {
+			i++
+		}
+		if i >= end && j >= deltaEnd {
+			break
+		}
 
-	if len(truthy) == 0 {
-		return NewBoolColumn()
+		// Main store wins ties, it was ingested first
+		if j >= deltaEnd || (i < end &&
+			!proj.delta[j].Time.Before(proj.Times.Access(i))) {
+			positions = append(positions, i)
+			i++
+		} else {
+			positions = append(positions, main+j)
+			j++
+		}
 	}
 
-	// Find last truthy index, our sort invariant
-	// has that as the point with the latest time
-	lastIndex := truthy[len(truthy)-1]
-	// Offset latestTime by a small amount so we can
-	// be perform an After easily
-	latestTime := proj.Times.Access(lastIndex).Add(-time.Minute)
+	return positions
+}
 
-	proj.Times.ANDAfter(latestTime, query)
+// Walk the projection one name at a time in name order,
+// handing over each name's positions in ascending order of time
+//
+// Each name's rows in the main store are a single run, found
+// without visiting the rows within it. Delta rows for the name
+// are merged in by time. Names whose rows have all been removed
+// are skipped. The positions slice is reused between calls so
+// must not be retained.
+func (proj *NameTimeProjection) eachName(fn func(name string, positions []int)) {
+	main := proj.MainLength()
+	positions := make([]int, 0)
 
-	return query
+	// Emit a name holding main rows [start, end)
+	// and delta rows [deltaStart, deltaEnd)
+	emit := func(name string, start, end, deltaStart, deltaEnd int) {
+		positions = proj.mergeByTime(positions[:0], start, end,
+			deltaStart, deltaEnd)
+		if len(positions) > 0 {
+			fn(name, positions)
+		}
+	}
+
+	nameKey := SortKey{Field: FieldName}
+	deltaAt := func(i int) PriceTuple {
+		return proj.delta[i]
+	}
+
+	d := 0
+	for start := 0; start < main; {
+		name := proj.Names.Access(start)
+
+		end := start
+		if _, runEnd, ok := proj.runAt(FieldName, start); ok {
+			end = runEnd
+		} else {
+			at := func(i int) PriceTuple {
+				return proj.fieldAt(FieldName, i)
+			}
+			_, end = narrowRange(start, main, at, nameKey, probeTuple(FieldName, name))
+		}
+
+		// Names only in the delta which sort before this one
+		for d < len(proj.delta) && proj.delta[d].Name < name {
+			deltaName := proj.delta[d].Name
+			_, deltaEnd := narrowRange(d, len(proj.delta), deltaAt,
+				nameKey, probeTuple(FieldName, deltaName))
+			emit(deltaName, 0, 0, d, deltaEnd)
+			d = deltaEnd
+		}
+
+		deltaEnd := d
+		if d < len(proj.delta) && proj.delta[d].Name == name {
+			_, deltaEnd = narrowRange(d, len(proj.delta), deltaAt,
+				nameKey, probeTuple(FieldName, name))
+		}
+		emit(name, start, end, d, deltaEnd)
+
+		start, d = end, deltaEnd
+	}
+
+	// Names only in the delta which sort after every main name
+	for d < len(proj.delta) {
+		deltaName := proj.delta[d].Name
+		_, deltaEnd := narrowRange(d, len(proj.delta), deltaAt,
+			nameKey, probeTuple(FieldName, deltaName))
+		emit(deltaName, 0, 0, d, deltaEnd)
+		d = deltaEnd
+	}
+}
+
+// Determine the leading position of the final time group
+// of positions in ascending order of time
+//
+// As with Latest, the group covers anything within
+// a minute of the latest time, to include a whole scrape.
+func (proj *NameTimeProjection) finalGroup(positions []int) int {
+	latestTime := proj.timeAt(positions[len(positions)-1])
+	cutoff := latestTime.Add(-time.Minute)
+
+	first := len(positions) - 1
+	for first > 0 && proj.timeAt(positions[first-1]).After(cutoff) {
+		first--
+	}
+	return first
 }
 
-type NameTimeOrderedTuples []PriceTuple
+// Query for the latest group of prices of every card in one pass
+//
+// Walks the projection run by run, emitting the final time
+// group of each name, which is the same group Latest finds.
+func (proj *NameTimeProjection) LatestAll() map[string][]PriceTuple {
+	results := make(map[string][]PriceTuple)
+
+	proj.eachName(func(name string, positions []int) {
+		first := proj.finalGroup(positions)
 
-func (a NameTimeOrderedTuples) Len() int {
-	return len(a)
+		group := make([]PriceTuple, 0, len(positions)-first)
+		for _, p := range positions[first:] {
+			group = append(group, proj.Access(p))
+		}
+		results[name] = group
+	})
+
+	return results
 }
-func (a NameTimeOrderedTuples) Swap(i, j int) {
-	a[i], a[j] = a[j], a[i]
+
+// Query for the latest price of every card in every set
+// it is printed in, in one pass
+//
+// Sets can be scraped at different times, so a set's latest
+// price may predate the card's final time group.
+func (proj *NameTimeProjection) LatestAllBySet() map[NameSet]PriceTuple {
+	results := make(map[NameSet]PriceTuple)
+
+	proj.eachName(func(name string, positions []int) {
+		// Walk backwards, the first row seen for
+		// each set is that set's latest
+		for i := len(positions) - 1; i >= 0; i-- {
+			key := NameSet{name, proj.setAt(positions[i])}
+			if _, ok := results[key]; !ok {
+				results[key] = proj.Access(positions[i])
+			}
+		}
+	})
+
+	return results
 }
-func (a NameTimeOrderedTuples) Less(i, j int) bool {
-	first := a[i]
-	second := a[j]
 
-	// If names are same, fall back to time based
-	if first.Name == second.Name {
-		return first.Time.Before(second.Time)
+// Query for the group of prices of a card effective at a point
+// in time, being its most recent group at or before that time
+//
+// Groups are determined as in Latest. Both stores are searched
+// so this costs O(log n) plus the size of the group.
+func (proj *NameTimeProjection) AsOf(name string, when time.Time) []PriceTuple {
+	// Everything up to and including when
+	var epoch time.Time
+	until := when.Add(time.Nanosecond)
+
+	start, end := proj.KeyRange(name)
+	start, end = proj.TimeRange(start, end, epoch, until)
+	deltaStart, deltaEnd := proj.DeltaKeyRange(name)
+	deltaStart, deltaEnd = proj.DeltaTimeRange(deltaStart, deltaEnd, epoch, until)
+
+	// The last row of each range holds its latest time
+	var effective time.Time
+	found := false
+	if last, ok := proj.lastLive(start, end); ok {
+		effective = proj.Times.Access(last)
+		found = true
+	}
+	if deltaStart < deltaEnd {
+		deltaEffective := proj.delta[deltaEnd-1].Time
+		if !found || deltaEffective.After(effective) {
+			effective = deltaEffective
+			found = true
+		}
+	}
+
+	if !found {
+		return []PriceTuple{}
+	}
+
+	from := effective.Add(-time.Minute).Add(time.Nanosecond)
+	start, end = proj.TimeRange(start, end, from, until)
+	deltaStart, deltaEnd = proj.DeltaTimeRange(deltaStart, deltaEnd, from, until)
+
+	// Merge the stores by time, as AsOfAll sees them
+	positions := proj.mergeByTime(make([]int, 0, end-start+deltaEnd-deltaStart),
+		start, end, deltaStart, deltaEnd)
+
+	group := make([]PriceTuple, len(positions))
+	for i, p := range positions {
+		group[i] = proj.Access(p)
 	}
+	return group
+}
+
+// Query for the group of prices of every card effective
+// at a point in time, in one pass
+//
+// Cards with no prices at or before that time are omitted.
+func (proj *NameTimeProjection) AsOfAll(when time.Time) map[string][]PriceTuple {
+	results := make(map[string][]PriceTuple)
+
+	proj.eachName(func(name string, positions []int) {
+		// Positions are in time order, so find the
+		// first position after when by binary search
+		effective := sort.Search(len(positions), func(i int) bool {
+			return proj.timeAt(positions[i]).After(when)
+		})
+		if effective == 0 {
+			return
+		}
+
+		positions = positions[:effective]
+		first := proj.finalGroup(positions)
+
+		group := make([]PriceTuple, 0, len(positions)-first)
+		for _, p := range positions[first:] {
+			group = append(group, proj.Access(p))
+		}
+		results[name] = group
+	})
 
-	return first.Name < second.Name
+	return results
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"time"
+)
+
+// Open, high, low and close prices of a card over one bucket
+type OHLCBar struct {
+	Name string
+
+	// Start of the bucket
+	Bucket time.Time
+
+	Open, High, Low, Close uint32
+
+	// Number of prices within the bucket
+	Count int
+}
+
+// Downsample every card's prices into fixed width time buckets
+//
+// Buckets are aligned with time.Truncate, so a width of a day
+// starts at midnight UTC and a width of a week starts on Monday.
+// Only sets named in the filter contribute, a nil filter allows
+// every set. Bars are produced in order of name then bucket,
+// reading only the time, price and set of each row.
+func (proj *NameTimeProjection) OHLC(width time.Duration, sets []string) []OHLCBar {
+	var allowed map[string]bool
+	if sets != nil {
+		allowed = make(map[string]bool)
+		for _, set := range sets {
+			allowed[set] = true
+		}
+	}
+
+	bars := make([]OHLCBar, 0)
+
+	proj.eachName(func(name string, positions []int) {
+		var bar OHLCBar
+		for _, p := range positions {
+			if allowed != nil && !allowed[proj.setAt(p)] {
+				continue
+			}
+
+			bucket := proj.timeAt(p).UTC().Truncate(width)
+			price := proj.priceAt(p)
+
+			// Positions are in time order, so a new
+			// bucket closes the previous one
+			if bar.Count == 0 || !bucket.Equal(bar.Bucket) {
+				if bar.Count > 0 {
+					bars = append(bars, bar)
+				}
+				bar = OHLCBar{
+					Name:   name,
+					Bucket: bucket,
+					Open:   price,
+					High:   price,
+					Low:    price,
+				}
+			}
+
+			if price > bar.High {
+				bar.High = price
+			}
+			if price < bar.Low {
+				bar.Low = price
+			}
+			bar.Close = price
+			bar.Count++
+		}
+
+		if bar.Count > 0 {
+			bars = append(bars, bar)
+		}
+	})
+
+	return bars
+}
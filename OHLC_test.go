@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Daily bars hold each card's single scrape per day
+// while a weekly bar spans all three days
+func TestNameTimeProjectionOHLC(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	daily := proj.OHLC(24*time.Hour, []string{"Avacyn Restored"})
+
+	// Avacyn and Griselbrand, three days each
+	if len(daily) != 6 {
+		t.Fatalf("found %v daily bars, expected 6: %v", len(daily), daily)
+	}
+	for _, bar := range daily {
+		if bar.Count != 1 || bar.Open != bar.Close || bar.High != bar.Low {
+			t.Fatalf("unexpected daily bar %v", bar)
+		}
+		if bar.Bucket.Hour() != 0 {
+			t.Fatalf("bucket not aligned to midnight %v", bar.Bucket)
+		}
+	}
+
+	// 2016-03-01 through 03 share a week starting Monday 02-29
+	weekly := proj.OHLC(7*24*time.Hour, nil)
+	if len(weekly) != 3 {
+		t.Fatalf("found %v weekly bars, expected 3: %v", len(weekly), weekly)
+	}
+
+	griselbrand := weekly[1]
+	if griselbrand.Name != "Griselbrand" || griselbrand.Count != 5 {
+		t.Fatalf("unexpected weekly bar %v", griselbrand)
+	}
+	if griselbrand.Open != 2100 || griselbrand.High != 5523 ||
+		griselbrand.Low != 2000 || griselbrand.Close != 2200 {
+		t.Fatalf("unexpected weekly bar %v", griselbrand)
+	}
+	if griselbrand.Bucket.Weekday() != time.Monday {
+		t.Fatalf("week bucket starts on %v", griselbrand.Bucket.Weekday())
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+)
+
+// Identifies one of the columns every PriceDB and
+// projection of it carries
+type PriceField uint32
+
+const (
+	FieldName PriceField = iota
+	FieldSet
+	FieldPrice
+	FieldTime
+)
+
+func (f PriceField) String() string {
+	switch f {
+	case FieldName:
+		return "name"
+	case FieldSet:
+		return "set"
+	case FieldPrice:
+		return "price"
+	case FieldTime:
+		return "time"
+	}
+	return "unknown"
+}
+
+// A single ORDER BY term
+//
+// The zero value sorts by name ascending.
+type SortKey struct {
+	Field PriceField
+	Desc  bool
+}
+
+func (k SortKey) String() string {
+	if k.Desc {
+		return k.Field.String() + " DESC"
+	}
+	return k.Field.String() + " ASC"
+}
+
+// Compare two tuples on this key alone
+//
+// Returns a negative number when a sorts before b, zero when
+// they tie and a positive number when a sorts after b.
+func (k SortKey) compareTuples(a, b PriceTuple) int {
+	var result int
+	switch k.Field {
+	case FieldName:
+		result = strings.Compare(a.Name, b.Name)
+	case FieldSet:
+		result = strings.Compare(a.Set, b.Set)
+	case FieldPrice:
+		result = compareUInt32(a.Price, b.Price)
+	case FieldTime:
+		result = compareInt64(a.Time.UnixNano(), b.Time.UnixNano())
+	}
+
+	if k.Desc {
+		return -result
+	}
+	return result
+}
+
+// Compare two tuples on every key in order, falling
+// through to the next key on ties
+func compareTuplesByKeys(keys []SortKey, a, b PriceTuple) int {
+	for _, k := range keys {
+		if result := k.compareTuples(a, b); result != 0 {
+			return result
+		}
+	}
+
+	return 0
+}
+
+func compareUInt32(a, b uint32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compare two rows of this database on a single key
+//
+// Equal dictionary codes short circuit the string comparison.
+func (db *PriceDB) compareRows(k SortKey, i, j int) int {
+	var result int
+	switch k.Field {
+	case FieldName:
+		if db.Names.contents.Access(i) != db.Names.contents.Access(j) {
+			result = strings.Compare(db.Names.Access(i), db.Names.Access(j))
+		}
+	case FieldSet:
+		if db.Sets.contents.Access(i) != db.Sets.contents.Access(j) {
+			result = strings.Compare(db.Sets.Access(i), db.Sets.Access(j))
+		}
+	case FieldPrice:
+		result = compareUInt32(db.Prices.Access(i), db.Prices.Access(j))
+	case FieldTime:
+		result = compareInt64(db.Times.Access(i).UnixNano(),
+			db.Times.Access(j).UnixNano())
+	}
+
+	if k.Desc {
+		return -result
+	}
+	return result
+}
+
+// Compare two rows of this database on every key in order
+//
+// Remaining ties are broken by position so results are
+// deterministic regardless of heap or sort internals.
+func (db *PriceDB) compareRowsByKeys(keys []SortKey, i, j int) int {
+	for _, k := range keys {
+		if result := db.compareRows(k, i, j); result != 0 {
+			return result
+		}
+	}
+
+	switch {
+	case i < j:
+		return -1
+	case i > j:
+		return 1
+	}
+	return 0
+}
+
+// Materialize all PriceTuples that are truthy from the provided
+// BoolColumn then sort them by the provided keys
+//
+// Equivalent to SELECT * ... ORDER BY keys. The sort is stable
+// so rows tying on every key remain in storage order.
+func (db *PriceDB) OrderBy(b BoolColumn, keys []SortKey) []PriceTuple {
+
+	tuples := db.MaterializeFromBools(b)
+
+	sortTuples(tuples, keys)
+
+	return tuples
+}
+
+// Stably sort tuples in place by the provided keys
+func sortTuples(tuples []PriceTuple, keys []SortKey) {
+	sort.Stable(KeyOrderedTuples{tuples, keys})
+}
+
+// Determine the first k PriceTuples, according to the provided
+// keys, that are truthy from the provided BoolColumn
+//
+// Equivalent to SELECT * ... ORDER BY keys LIMIT k. Rather than
+// sorting the selection we keep a bounded heap of the k best
+// positions seen so far, so only k tuples are ever materialized.
+func (db *PriceDB) TopK(b BoolColumn, keys []SortKey, k int) []PriceTuple {
+	if k <= 0 {
+		return []PriceTuple{}
+	}
+
+	// The heap keeps the worst of our current candidates on top
+	// so it can be evicted as better rows show up
+	candidates := &rowHeap{
+		positions: make([]int, 0, k),
+		worse: func(i, j int) bool {
+			return db.compareRowsByKeys(keys, i, j) > 0
+		},
+	}
+
+	for p, ok := b.NextTruthy(0); ok; p, ok = b.NextTruthy(p + 1) {
+		if db.deleted.Deleted(p) {
+			continue
+		}
+
+		if candidates.Len() < k {
+			heap.Push(candidates, p)
+			continue
+		}
+
+		if candidates.worse(candidates.positions[0], p) {
+			candidates.positions[0] = p
+			heap.Fix(candidates, 0)
+		}
+	}
+
+	// Pop from worst to best, filling from the back
+	positions := make([]int, candidates.Len())
+	for i := len(positions) - 1; i >= 0; i-- {
+		positions[i] = heap.Pop(candidates).(int)
+	}
+
+	return db.MaterializePositions(positions)
+}
+
+// A heap of row positions ordered by an arbitrary comparison
+type rowHeap struct {
+	positions []int
+	worse     func(i, j int) bool
+}
+
+func (h *rowHeap) Len() int {
+	return len(h.positions)
+}
+func (h *rowHeap) Less(i, j int) bool {
+	return h.worse(h.positions[i], h.positions[j])
+}
+func (h *rowHeap) Swap(i, j int) {
+	h.positions[i], h.positions[j] = h.positions[j], h.positions[i]
+}
+func (h *rowHeap) Push(x interface{}) {
+	h.positions = append(h.positions, x.(int))
+}
+func (h *rowHeap) Pop() interface{} {
+	last := h.positions[len(h.positions)-1]
+	h.positions = h.positions[:len(h.positions)-1]
+	return last
+}
+
+// Tuples ordered by an arbitrary list of sort keys
+type KeyOrderedTuples struct {
+	Tuples []PriceTuple
+	Keys   []SortKey
+}
+
+func (a KeyOrderedTuples) Len() int {
+	return len(a.Tuples)
+}
+func (a KeyOrderedTuples) Swap(i, j int) {
+	a.Tuples[i], a.Tuples[j] = a.Tuples[j], a.Tuples[i]
+}
+func (a KeyOrderedTuples) Less(i, j int) bool {
+	return compareTuplesByKeys(a.Keys, a.Tuples[i], a.Tuples[j]) < 0
+}
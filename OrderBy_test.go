@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+)
+
+// Order every row by price descending then name ascending
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice ORDER BY price DESC, name ASC;
+func TestOrderByPriceDescName(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	keys := []SortKey{
+		{Field: FieldPrice, Desc: true},
+		{Field: FieldName},
+	}
+	tuples := db.OrderBy(allRows(db.Prices.Length()), keys)
+	if len(tuples) != db.Prices.Length() {
+		t.Fatalf("ordered %v tuples, expected %v", len(tuples), db.Prices.Length())
+	}
+
+	for i := 1; i < len(tuples); i++ {
+		if tuples[i-1].Price < tuples[i].Price {
+			t.Fatalf("not desc price order at %v: %v then %v",
+				i, tuples[i-1], tuples[i])
+		}
+	}
+
+	if tuples[0].Price != 15499 || tuples[len(tuples)-1].Price != 2000 {
+		t.Fatalf("unexpected extremes %v, %v", tuples[0], tuples[len(tuples)-1])
+	}
+}
+
+// Order a selection by name, then set, then time descending
+func TestOrderByMultiKey(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	keys := []SortKey{
+		{Field: FieldName},
+		{Field: FieldSet},
+		{Field: FieldTime, Desc: true},
+	}
+	query := db.Names.Equal("Griselbrand")
+	tuples := db.OrderBy(query, keys)
+	if len(tuples) != 5 {
+		t.Fatalf("ordered %v tuples, expected 5", len(tuples))
+	}
+
+	expected := []uint32{2200, 2000, 2100, 5523, 5200}
+	for i, price := range expected {
+		if tuples[i].Price != price {
+			t.Fatalf("unexpected order %v", tuples)
+		}
+	}
+}
+
+// Top-K must agree with a full sort truncated to K
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice ORDER BY price DESC LIMIT 3;
+func TestTopKMatchesOrderBy(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	keys := []SortKey{{Field: FieldPrice, Desc: true}}
+	all := allRows(db.Prices.Length())
+
+	sorted := db.OrderBy(all, keys)
+	for _, k := range []int{0, 1, 3, len(sorted), len(sorted) + 5} {
+		top := db.TopK(all, keys, k)
+
+		expectedLength := k
+		if expectedLength > len(sorted) {
+			expectedLength = len(sorted)
+		}
+		if len(top) != expectedLength {
+			t.Fatalf("top %v returned %v tuples", k, len(top))
+		}
+
+		for i := range top {
+			if top[i] != sorted[i] {
+				t.Fatalf("top %v differs at %v: %v != %v", k, i, top[i], sorted[i])
+			}
+		}
+	}
+}
+
+// Deleted rows are never among the top K
+func TestTopKSkipsDeleted(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	db.Delete(db.Names.Equal("Windswept Heath"))
+
+	keys := []SortKey{{Field: FieldPrice, Desc: true}}
+	all := allRows(db.Prices.Length())
+
+	top := db.TopK(all, keys, 2)
+	sorted := db.OrderBy(all, keys)
+	if len(top) != 2 {
+		t.Fatalf("top 2 returned %v tuples", len(top))
+	}
+	for i := range top {
+		if top[i].Name == "Windswept Heath" || top[i] != sorted[i] {
+			t.Fatalf("top 2 differs at %v: %v != %v", i, top[i], sorted[i])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Run fn over every row group of a length row table,
+// spreading groups across at most GOMAXPROCS workers and
+// returning once all have finished
+//
+// Groups are independent units of work, so per-group partials
+// may be kept in a slice as long as the number of groups.
+func eachGroup(length int, fn func(group int, r RowRange)) {
+	groups := rowGroups(length)
+	workers := runtime.GOMAXPROCS(0)
+	if groups < workers {
+		workers = groups
+	}
+	if workers <= 1 {
+		for g := 0; g < groups; g++ {
+			fn(g, groupRange(g, length))
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for g := w; g < groups; g += workers {
+				fn(g, groupRange(g, length))
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// Evaluate a predicate over every row group in parallel
+// and return the results positionally as a BoolColumn
+//
+// fill receives the words of the result covering exactly
+// its group's rows. Row groups are a whole number of words
+// so no two groups ever write the same word.
+func scanGroups(length int, fill func(group int, words []uint64)) BoolColumn {
+	words := make([]uint64, (length+63)/64)
+
+	eachGroup(length, func(group int, r RowRange) {
+		fill(group, words[r.Start/64:(r.End+63)/64])
+	})
+
+	return BoolColumnFromWords(words, length)
+}
+
+// Narrow an existing result over every row group in parallel
+//
+// clear receives the words of results covering its group's
+// rows, which it rewrites in place. Rows results does not
+// hold are already false so are not visited.
+func andGroups(length int, results BoolColumn, clear func(group int, words []uint64)) {
+	words := results.contents.Bytes()
+
+	eachGroup(length, func(group int, r RowRange) {
+		start, end := r.Start/64, (r.End+63)/64
+		if start >= len(words) {
+			return
+		}
+		if end > len(words) {
+			end = len(words)
+		}
+		clear(group, words[start:end])
+	})
+}
+
+// Pack test of rows [0, n) into words, 64 rows to a word
+func packWords(words []uint64, n int, test func(i int) bool) {
+	for w := range words {
+		var bits uint64
+		for b, i := uint(0), w*64; b < 64 && i < n; b, i = b+1, i+1 {
+			if test(i) {
+				bits |= 1 << b
+			}
+		}
+		words[w] = bits
+	}
+}
+
+// Clear the bits of rows [0, n) in words failing test
+func andWords(words []uint64, n int, test func(i int) bool) {
+	for w, bits := range words {
+		if bits == 0 {
+			continue
+		}
+		for b, i := uint(0), w*64; b < 64 && i < n; b, i = b+1, i+1 {
+			if bits&(1<<b) != 0 && !test(i) {
+				bits &^= 1 << b
+			}
+		}
+		words[w] = bits
+	}
+}
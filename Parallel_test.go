@@ -0,0 +1,97 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRowGroupRanges(t *testing.T) {
+	for _, length := range []int{0, 1, 63, RowGroupSize, RowGroupSize*3 + 37} {
+		covered := 0
+		for g := 0; g < rowGroups(length); g++ {
+			r := groupRange(g, length)
+			if r.Start != covered || r.Start%64 != 0 {
+				t.Fatalf("length %v: bad range %v", length, r)
+			}
+			covered = r.End
+		}
+		if covered != length {
+			t.Fatalf("length %v: covered only %v", length, covered)
+		}
+	}
+}
+
+// Parallel scans must agree with a plain loop over
+// enough rows to use every worker
+//
+// Run with -race to check workers stay in their own words.
+func TestParallelPredicates(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	const length = RowGroupSize*3 + 37
+
+	prices := NewUInt32Column()
+	times := NewTimeColumn()
+	values := make([]uint32, length)
+	stamps := make([]time.Time, length)
+	var sum uint64
+	for i := range values {
+		values[i] = uint32((i * 7919) % 1000)
+		stamps[i] = time.Unix(int64(values[i]), 0)
+		sum += uint64(values[i])
+	}
+	prices.Push(values)
+	times.Push(stamps)
+
+	check := func(name string, results BoolColumn, expect func(v uint32) bool) {
+		if results.end != length {
+			t.Fatalf("%v: bad length, %v", name, results.end)
+		}
+		for i, v := range values {
+			if results.contents.Test(uint(i)) != expect(v) {
+				t.Fatalf("%v: wrong result at %v", name, i)
+			}
+		}
+	}
+
+	check("less", prices.Less(500), func(v uint32) bool { return v < 500 })
+	check("more", prices.More(500), func(v uint32) bool { return v >= 500 })
+	check("equal", prices.Equal(42), func(v uint32) bool { return v == 42 })
+	check("after", times.After(time.Unix(900, 0)),
+		func(v uint32) bool { return v > 900 })
+
+	between := times.Before(time.Unix(600, 0))
+	times.ANDAfter(time.Unix(300, 0), between)
+	check("between", between, func(v uint32) bool { return v > 300 && v < 600 })
+
+	if prices.Sum() != sum {
+		t.Fatalf("bad sum, %v != %v", prices.Sum(), sum)
+	}
+}
+
+// Postgres equivalent
+//  SELECT name, SUM(price) FROM prices.mtgprice
+//  	WHERE set = 'Avacyn Restored' GROUP BY name;
+func TestSumPricesBy(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	sums, err := db.SumPricesBy(db.Sets.Equal("Avacyn Restored"), FieldName)
+	if err != nil {
+		t.Fatalf("failed to group: %v", err)
+	}
+	if len(sums) != 2 || sums["Griselbrand"] != 6300 ||
+		sums["Avacyn, Angel of Hope"] != 9000 {
+		t.Fatalf("bad sums, %v", sums)
+	}
+
+	db.Delete(db.Prices.Equal(2100))
+	sums, _ = db.SumPricesBy(db.Live(), FieldSet)
+	if sums["Avacyn Restored"] != 13200 || sums["Onslaught Foil"] != 15499 {
+		t.Fatalf("bad sums after delete, %v", sums)
+	}
+
+	if _, err := db.SumPricesBy(db.Live(), FieldPrice); err == nil {
+		t.Fatalf("grouped by price")
+	}
+}
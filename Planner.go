@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The comparison a Predicate performs
+type Comparison uint32
+
+const (
+	OpEqual Comparison = iota
+	OpLess
+	OpMore
+)
+
+func (op Comparison) String() string {
+	switch op {
+	case OpEqual:
+		return "="
+	case OpLess:
+		return "<"
+	case OpMore:
+		return ">"
+	}
+	return "?"
+}
+
+// A single condition of a Query's WHERE clause
+//
+// Values must be a string for names and sets, a uint32 for
+// prices and a time.Time for times. Strings only support OpEqual.
+type Predicate struct {
+	Field PriceField
+	Op    Comparison
+	Value interface{}
+}
+
+func (p Predicate) String() string {
+	switch v := p.Value.(type) {
+	case string:
+		return fmt.Sprintf("%v %v %q", p.Field, p.Op, v)
+	case time.Time:
+		return fmt.Sprintf("%v %v %v", p.Field, p.Op,
+			v.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("%v %v %v", p.Field, p.Op, p.Value)
+}
+
+// Ensure the predicate's value suits its field
+func (p Predicate) validate() error {
+	ok := false
+	switch p.Field {
+	case FieldName, FieldSet:
+		_, ok = p.Value.(string)
+		if ok && p.Op != OpEqual {
+			return fmt.Errorf("unsupported comparison '%v'", p)
+		}
+	case FieldPrice:
+		_, ok = p.Value.(uint32)
+	case FieldTime:
+		_, ok = p.Value.(time.Time)
+	}
+
+	if !ok {
+		return fmt.Errorf("mismatched value for field '%v'", p)
+	}
+	return nil
+}
+
+// Determine if a single tuple satisfies the predicate
+func (p Predicate) matches(t PriceTuple) bool {
+	switch p.Field {
+	case FieldName:
+		return t.Name == p.Value.(string)
+	case FieldSet:
+		return t.Set == p.Value.(string)
+	case FieldPrice:
+		value := p.Value.(uint32)
+		switch p.Op {
+		case OpLess:
+			return t.Price < value
+		case OpMore:
+			return t.Price > value
+		}
+		return t.Price == value
+	case FieldTime:
+		value := p.Value.(time.Time)
+		switch p.Op {
+		case OpLess:
+			return t.Time.Before(value)
+		case OpMore:
+			return t.Time.After(value)
+		}
+		return t.Time.Equal(value)
+	}
+	return false
+}
+
+// Evaluate the predicate against every row of a database
+func (p Predicate) evaluate(db *PriceDB) BoolColumn {
+	switch p.Field {
+	case FieldName:
+		return db.Names.Equal(p.Value.(string))
+	case FieldSet:
+		return db.Sets.Equal(p.Value.(string))
+	case FieldPrice:
+		value := p.Value.(uint32)
+		switch p.Op {
+		case OpLess:
+			return db.Prices.Less(value)
+		case OpMore:
+			// More includes the value itself
+			query := db.Prices.More(value)
+			equal := db.Prices.Equal(value)
+			return query.AND(db.Not(equal))
+		}
+		return db.Prices.Equal(value)
+	}
+
+	value := p.Value.(time.Time)
+	switch p.Op {
+	case OpLess:
+		return db.Times.Before(value)
+	case OpMore:
+		return db.Times.After(value)
+	}
+	query := db.Live()
+	db.Times.ANDAfter(value.Add(-time.Nanosecond), query)
+	db.Times.ANDBefore(value.Add(time.Nanosecond), query)
+	return query
+}
+
+// Select every row of a database
+func allRows(length int) BoolColumn {
+	query := NewBoolColumn()
+	query.PushTrue(length)
+
+	return query
+}
+
+// A declarative query against a PriceDB
+//
+// Equivalent to SELECT * ... WHERE where ORDER BY orderBy LIMIT limit
+// with every predicate ANDed together. A zero Limit returns every row.
+type Query struct {
+	Where   []Predicate
+	OrderBy []SortKey
+	Limit   int
+}
+
+// Projections attached to a PriceDB
+type ProjectionRegistry struct {
+	projections []*Projection
+}
+
+// Build a projection of this database and attach it
+//
+// Attached projections receive every later Push
+// and are considered when planning queries.
+func (db *PriceDB) Attach(spec ProjectionSpec) *Projection {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	proj := ProjectionFromPriceDB(*db, spec)
+	db.projections.projections = append(db.projections.projections, &proj)
+
+	return &proj
+}
+
+// Attach an existing projection of this database
+//
+// The projection must already hold every row of the database.
+func (db *PriceDB) Register(proj *Projection) {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	db.projections.projections = append(db.projections.projections, proj)
+}
+
+// Determine which projections are attached to this database
+func (db *PriceDB) AttachedProjections() []*Projection {
+	return db.projections.projections
+}
+
+// How a query will be answered
+type Plan struct {
+	Query Query
+
+	// The projection the query runs against,
+	// nil when running against the PriceDB itself
+	Projection *Projection
+
+	// Rows of the source the query is confined to
+	start, end int
+
+	// Indices of the predicates answered by the range
+	ranged []int
+
+	// Whether the source produces rows in the requested
+	// order, either directly or when walked backwards
+	ordered  bool
+	reversed bool
+
+	// Rough estimate of rows touched
+	Cost float64
+}
+
+// Choose the cheapest way to answer a query
+//
+// Every attached projection is considered alongside the
+// PriceDB itself, which wins ties.
+func (db *PriceDB) Plan(q Query) (Plan, error) {
+	for _, p := range q.Where {
+		if err := p.validate(); err != nil {
+			return Plan{}, err
+		}
+	}
+
+	best := db.planBase(q)
+	for _, proj := range db.projections.projections {
+		candidate := planProjection(proj, q)
+		if candidate.Cost < best.Cost {
+			best = candidate
+		}
+	}
+
+	return best, nil
+}
+
+// Plan a query as full column scans of the database
+func (db *PriceDB) planBase(q Query) Plan {
+	length := db.Prices.Length()
+
+	plan := Plan{
+		Query: q,
+		end:   length,
+	}
+
+	scans := len(q.Where)
+	if scans == 0 {
+		scans = 1
+	}
+	plan.Cost = float64(length*scans) + sortCost(length, q)
+
+	return plan
+}
+
+// Plan a query against a sorted projection
+//
+// Equality on a prefix of the sort keys confines the query to
+// the range found by binary search. Orderings which match the
+// sort order need no sort and may stop once the limit is reached.
+func planProjection(proj *Projection, q Query) Plan {
+	plan := Plan{
+		Query:      q,
+		Projection: proj,
+		end:        proj.MainLength(),
+	}
+
+	// Fix as many leading sort keys as we have equalities for
+	keys := proj.Spec.SortKeys
+	values := make([]interface{}, 0)
+	for len(keys) > 0 {
+		i := equalityOn(q.Where, keys[0].Field)
+		if i < 0 {
+			break
+		}
+
+		values = append(values, q.Where[i].Value)
+		plan.ranged = append(plan.ranged, i)
+		keys = keys[1:]
+	}
+
+	// Cost of locating a range, if we can use one
+	lookup := 0.0
+	if len(values) > 0 {
+		plan.start, plan.end = proj.KeyRange(values...)
+		lookup = float64(len(values)) * math.Log2(float64(proj.MainLength())+1)
+	}
+
+	plan.ordered, plan.reversed = orderSatisfied(keys, q.OrderBy)
+
+	scanned := plan.end - plan.start
+	filters := len(q.Where) - len(plan.ranged)
+	if plan.ordered && q.Limit > 0 && filters == 0 && q.Limit < scanned {
+		scanned = q.Limit
+	}
+	if filters == 0 {
+		filters = 1
+	}
+
+	plan.Cost = lookup + float64(scanned*filters) + float64(proj.DeltaLength())
+	if !plan.ordered {
+		plan.Cost += sortCost(scanned, q)
+	}
+
+	return plan
+}
+
+// Find the first equality predicate on a field, or -1
+func equalityOn(predicates []Predicate, field PriceField) int {
+	for i, p := range predicates {
+		if p.Field == field && p.Op == OpEqual {
+			return i
+		}
+	}
+	return -1
+}
+
+// Determine if a predicate is answered by the plan's range
+func (plan Plan) isRanged(predicate int) bool {
+	for _, i := range plan.ranged {
+		if i == predicate {
+			return true
+		}
+	}
+	return false
+}
+
+// Determine if rows sorted by keys are already in the requested
+// order, or in the requested order when walked backwards
+func orderSatisfied(keys, requested []SortKey) (ordered, reversed bool) {
+	if len(requested) == 0 {
+		return true, false
+	}
+	if len(requested) > len(keys) {
+		return false, false
+	}
+
+	forwards, backwards := true, true
+	for i, k := range requested {
+		if keys[i].Field != k.Field {
+			return false, false
+		}
+		if keys[i].Desc == k.Desc {
+			backwards = false
+		} else {
+			forwards = false
+		}
+	}
+
+	if forwards {
+		return true, false
+	}
+	return backwards, backwards
+}
+
+// Estimate the cost of ordering rows for a query
+func sortCost(rows int, q Query) float64 {
+	if len(q.OrderBy) == 0 || rows == 0 {
+		return 0
+	}
+
+	// A limit lets us keep a bounded heap instead
+	if q.Limit > 0 && q.Limit < rows {
+		return float64(rows) * math.Log2(float64(q.Limit)+1)
+	}
+	return float64(rows) * math.Log2(float64(rows)+1)
+}
+
+// Describe the plan, one step per line
+func (plan Plan) Explain() string {
+	var out bytes.Buffer
+	q := plan.Query
+
+	if plan.Projection == nil {
+		fmt.Fprintf(&out, "source: PriceDB\n")
+		fmt.Fprintf(&out, "access: full scan of %v rows\n", plan.end)
+	} else {
+		fmt.Fprintf(&out, "source: projection %v\n", plan.Projection.Spec.Name)
+		if len(plan.ranged) > 0 {
+			keys := ""
+			for n, i := range plan.ranged {
+				if n > 0 {
+					keys += ", "
+				}
+				keys += q.Where[i].String()
+			}
+			fmt.Fprintf(&out, "access: binary search on %v, rows [%v, %v)\n",
+				keys, plan.start, plan.end)
+		} else {
+			fmt.Fprintf(&out, "access: scan of rows [%v, %v)\n",
+				plan.start, plan.end)
+		}
+		if plan.Projection.DeltaLength() > 0 {
+			fmt.Fprintf(&out, "delta: scan of %v rows\n",
+				plan.Projection.DeltaLength())
+		}
+	}
+
+	for i, p := range q.Where {
+		if !plan.isRanged(i) {
+			fmt.Fprintf(&out, "filter: %v\n", p)
+		}
+	}
+
+	if len(q.OrderBy) > 0 {
+		order := ""
+		for i, k := range q.OrderBy {
+			if i > 0 {
+				order += ", "
+			}
+			order += k.String()
+		}
+
+		switch {
+		case plan.Projection != nil && plan.reversed:
+			fmt.Fprintf(&out, "order: %v, projection order reversed\n", order)
+		case plan.Projection != nil && plan.ordered:
+			fmt.Fprintf(&out, "order: %v, projection order\n", order)
+		case q.Limit > 0:
+			fmt.Fprintf(&out, "order: %v, top-k heap\n", order)
+		default:
+			fmt.Fprintf(&out, "order: %v, sort\n", order)
+		}
+	}
+
+	if q.Limit > 0 {
+		fmt.Fprintf(&out, "limit: %v\n", q.Limit)
+	}
+
+	fmt.Fprintf(&out, "cost: %.0f\n", plan.Cost)
+
+	return out.String()
+}
+
+// Plan and run a query
+func (db *PriceDB) Execute(q Query) ([]PriceTuple, error) {
+	plan, err := db.Plan(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.Projection == nil {
+		return db.runBase(q), nil
+	}
+	return plan.runProjection(), nil
+}
+
+// Answer a query with full column scans
+func (db *PriceDB) runBase(q Query) []PriceTuple {
+	query := db.Live()
+	for _, p := range q.Where {
+		query.AND(p.evaluate(db))
+	}
+
+	if len(q.OrderBy) > 0 && q.Limit > 0 {
+		return db.TopK(query, q.OrderBy, q.Limit)
+	}
+
+	tuples := db.OrderBy(query, q.OrderBy)
+	if q.Limit > 0 && q.Limit < len(tuples) {
+		tuples = tuples[:q.Limit]
+	}
+	return tuples
+}
+
+// Answer a query by walking the planned range of a projection
+// along with every row of its delta
+func (plan Plan) runProjection() []PriceTuple {
+	q := plan.Query
+	proj := plan.Projection
+
+	filters := make([]Predicate, 0, len(q.Where))
+	for i, p := range q.Where {
+		if !plan.isRanged(i) {
+			filters = append(filters, p)
+		}
+	}
+
+	// Walk the main store in the planned direction, stopping
+	// early when rows are already produced in order
+	matched := make([]PriceTuple, 0)
+	for n := 0; n < plan.end-plan.start; n++ {
+		i := plan.start + n
+		if plan.reversed {
+			i = plan.end - 1 - n
+		}
+
+		if proj.removed.Deleted(i) {
+			continue
+		}
+
+		tuple := proj.Access(i)
+		if matchesAll(filters, tuple) {
+			matched = append(matched, tuple)
+		}
+		if plan.ordered && q.Limit > 0 && len(matched) >= q.Limit {
+			break
+		}
+	}
+
+	// The delta is never ranged, so every predicate applies
+	fromDelta := make([]PriceTuple, 0)
+	for n := range proj.delta {
+		i := n
+		if plan.reversed {
+			i = len(proj.delta) - 1 - n
+		}
+
+		if matchesAll(q.Where, proj.delta[i]) {
+			fromDelta = append(fromDelta, proj.delta[i])
+		}
+	}
+
+	tuples := append(matched, fromDelta...)
+	if len(q.OrderBy) > 0 {
+		sortTuples(tuples, q.OrderBy)
+	}
+	if q.Limit > 0 && q.Limit < len(tuples) {
+		tuples = tuples[:q.Limit]
+	}
+	return tuples
+}
+
+// Determine if a tuple satisfies every predicate
+func matchesAll(predicates []Predicate, t PriceTuple) bool {
+	for _, p := range predicates {
+		if !p.matches(t) {
+			return false
+		}
+	}
+	return true
+}
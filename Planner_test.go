@@ -0,0 +1,164 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create the synthetic database with the name then time and
+// price projections attached to it
+func setupPlannerTest() PriceDB {
+	db := setupSyntheticPriceDB()
+	db.Attach(NameTimeSpec)
+	db.Attach(PriceSpec)
+
+	return db
+}
+
+// Answering a query with only the PriceDB must give the
+// same rows, in the same order of keys, as the chosen plan
+func checkAgainstBase(t *testing.T, db PriceDB, q Query) []PriceTuple {
+	planned, err := db.Execute(q)
+	if err != nil {
+		t.Fatalf("failed to execute '%v'", err)
+	}
+
+	base := db.runBase(q)
+	if len(planned) != len(base) {
+		t.Fatalf("planned found %v tuples, base found %v", len(planned), len(base))
+	}
+	for i := range base {
+		if compareTuplesByKeys(q.OrderBy, planned[i], base[i]) != 0 {
+			t.Fatalf("planned differs at %v: %v != %v", i, planned[i], base[i])
+		}
+	}
+
+	return planned
+}
+
+// Name equality goes to the name sorted projection and
+// is answered from the run holding that name
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice WHERE name = 'Griselbrand'
+//  	AND set = 'Avacyn Restored' ORDER BY time DESC;
+func TestPlanNameEquality(t *testing.T) {
+	db := setupPlannerTest()
+
+	q := Query{
+		Where: []Predicate{
+			{Field: FieldName, Op: OpEqual, Value: "Griselbrand"},
+			{Field: FieldSet, Op: OpEqual, Value: "Avacyn Restored"},
+		},
+		OrderBy: []SortKey{{Field: FieldTime, Desc: true}},
+	}
+	plan, err := db.Plan(q)
+	if err != nil {
+		t.Fatalf("failed to plan '%v'", err)
+	}
+
+	explained := plan.Explain()
+	if plan.Projection == nil || plan.Projection.Spec.Name != "name_time" {
+		t.Fatalf("expected name_time projection, got\n%v", explained)
+	}
+	if !strings.Contains(explained, "access: binary search on name = \"Griselbrand\"") ||
+		!strings.Contains(explained, "projection order reversed") {
+		t.Fatalf("unexpected explain output\n%v", explained)
+	}
+
+	tuples := checkAgainstBase(t, db, q)
+	if len(tuples) != 3 || tuples[0].Price != 2200 {
+		t.Fatalf("unexpected tuples %v", tuples)
+	}
+}
+
+// A descending price top-k walks the price projection backwards
+// and stops after the limit
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice ORDER BY price DESC LIMIT 3;
+func TestPlanTopKPrice(t *testing.T) {
+	db := setupPlannerTest()
+
+	q := Query{
+		OrderBy: []SortKey{{Field: FieldPrice, Desc: true}},
+		Limit:   3,
+	}
+	plan, err := db.Plan(q)
+	if err != nil {
+		t.Fatalf("failed to plan '%v'", err)
+	}
+	if plan.Projection == nil || plan.Projection.Spec.Name != "price" {
+		t.Fatalf("expected price projection, got\n%v", plan.Explain())
+	}
+
+	tuples := checkAgainstBase(t, db, q)
+	if tuples[0].Price != 15499 || tuples[2].Price != 9400 {
+		t.Fatalf("unexpected tuples %v", tuples)
+	}
+}
+
+// Unsorted range scans remain on the PriceDB itself
+//
+// Postgres equivalent
+//  SELECT * FROM prices.mtgprice WHERE price > 5200 AND price < 9500;
+func TestPlanPriceRange(t *testing.T) {
+	db := setupPlannerTest()
+
+	q := Query{
+		Where: []Predicate{
+			{Field: FieldPrice, Op: OpMore, Value: uint32(5200)},
+			{Field: FieldPrice, Op: OpLess, Value: uint32(9500)},
+		},
+	}
+	plan, err := db.Plan(q)
+	if err != nil {
+		t.Fatalf("failed to plan '%v'", err)
+	}
+	if plan.Projection != nil {
+		t.Fatalf("expected PriceDB, got\n%v", plan.Explain())
+	}
+
+	tuples := checkAgainstBase(t, db, q)
+	if len(tuples) != 3 {
+		t.Fatalf("found %v tuples, expected 3", len(tuples))
+	}
+}
+
+// Rows pushed after attaching are visible through projections
+func TestPlanSeesPushedRows(t *testing.T) {
+	db := setupPlannerTest()
+
+	when := time.Date(2016, time.March, 4, 3, 51, 45, 0, time.UTC)
+	db.Push([]PriceTuple{
+		{Name: "Griselbrand", Set: "Avacyn Restored", Price: 2300, Time: when},
+	})
+
+	q := Query{
+		Where: []Predicate{
+			{Field: FieldName, Op: OpEqual, Value: "Griselbrand"},
+		},
+		OrderBy: []SortKey{{Field: FieldTime, Desc: true}},
+		Limit:   1,
+	}
+	tuples := checkAgainstBase(t, db, q)
+	if len(tuples) != 1 || tuples[0].Price != 2300 {
+		t.Fatalf("pushed row not seen, got %v", tuples)
+	}
+}
+
+// Values must suit the field they are compared against
+func TestPlanInvalidPredicate(t *testing.T) {
+	db := setupPlannerTest()
+
+	invalid := []Predicate{
+		{Field: FieldPrice, Op: OpEqual, Value: "1458"},
+		{Field: FieldName, Op: OpLess, Value: "Griselbrand"},
+	}
+	for _, p := range invalid {
+		if _, err := db.Plan(Query{Where: []Predicate{p}}); err == nil {
+			t.Fatalf("accepted invalid predicate %v", p)
+		}
+	}
+}
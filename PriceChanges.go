@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// How a card's price in a set moved between two rows
+type PriceChange struct {
+	Name, Set string
+
+	// When each price was recorded
+	FromTime, ToTime time.Time
+
+	From, To uint32
+
+	// To - From, in cents
+	Absolute int64
+	// Absolute relative to From, as a percentage
+	Percent float64
+}
+
+func newPriceChange(name, set string, from, to PriceTuple) PriceChange {
+	change := PriceChange{
+		Name:     name,
+		Set:      set,
+		FromTime: from.Time,
+		ToTime:   to.Time,
+		From:     from.Price,
+		To:       to.Price,
+		Absolute: int64(to.Price) - int64(from.Price),
+	}
+
+	switch {
+	case from.Price != 0:
+		change.Percent = float64(change.Absolute) * 100 / float64(from.Price)
+	case to.Price != 0:
+		// Anything from nothing is an infinite change
+		change.Percent = math.Inf(1)
+	}
+
+	return change
+}
+
+// Determine how every card's price in each set changed
+// between two points in time
+//
+// Prices are those effective at each time, as with AsOf.
+// Pairs lacking a price at or before from are omitted.
+func (proj *NameTimeProjection) PriceChanges(from, to time.Time) []PriceChange {
+	changes := make([]PriceChange, 0)
+
+	proj.eachName(func(name string, positions []int) {
+		// Latest position per set at or before each time,
+		// in order of first appearance
+		before := make(map[string]int)
+		after := make(map[string]int)
+		sets := make([]string, 0)
+
+		for _, p := range positions {
+			when := proj.timeAt(p)
+			if when.After(to) {
+				break
+			}
+
+			set := proj.setAt(p)
+			if _, ok := after[set]; !ok {
+				sets = append(sets, set)
+			}
+			after[set] = p
+			if !when.After(from) {
+				before[set] = p
+			}
+		}
+
+		for _, set := range sets {
+			first, ok := before[set]
+			if !ok {
+				continue
+			}
+			changes = append(changes, newPriceChange(name, set,
+				proj.Access(first), proj.Access(after[set])))
+		}
+	})
+
+	return changes
+}
+
+// Determine how a card's price in each set changed between
+// each consecutive pair of its prices, in order of time
+func (proj *NameTimeProjection) ConsecutiveChanges(name string) []PriceChange {
+	history := proj.FullHistory(name)
+
+	changes := make([]PriceChange, 0)
+	previous := make(map[string]PriceTuple)
+	for _, tuple := range history {
+		if last, ok := previous[tuple.Set]; ok {
+			changes = append(changes, newPriceChange(name, tuple.Set, last, tuple))
+		}
+		previous[tuple.Set] = tuple
+	}
+
+	return changes
+}
+
+// The measure price changes are ordered by
+type ChangeMeasure uint32
+
+const (
+	ByAbsolute ChangeMeasure = iota
+	ByPercent
+	// Magnitude of the percentage change, regardless of direction
+	ByMovement
+)
+
+func (m ChangeMeasure) of(c PriceChange) float64 {
+	switch m {
+	case ByAbsolute:
+		return float64(c.Absolute)
+	case ByPercent:
+		return c.Percent
+	}
+	return math.Abs(c.Percent)
+}
+
+// Stably sort price changes in place by a measure
+func SortPriceChanges(changes []PriceChange, by ChangeMeasure, desc bool) {
+	sort.Stable(MeasureOrderedChanges{changes, by, desc})
+}
+
+// Determine the k biggest movers between two points in time,
+// being the largest changes in percentage either way
+func (proj *NameTimeProjection) TopMovers(from, to time.Time, k int) []PriceChange {
+	changes := proj.PriceChanges(from, to)
+	SortPriceChanges(changes, ByMovement, true)
+
+	if k < len(changes) {
+		changes = changes[:k]
+	}
+	return changes
+}
+
+// Price changes ordered by a measure
+type MeasureOrderedChanges struct {
+	Changes []PriceChange
+	By      ChangeMeasure
+	Desc    bool
+}
+
+func (a MeasureOrderedChanges) Len() int {
+	return len(a.Changes)
+}
+func (a MeasureOrderedChanges) Swap(i, j int) {
+	a.Changes[i], a.Changes[j] = a.Changes[j], a.Changes[i]
+}
+func (a MeasureOrderedChanges) Less(i, j int) bool {
+	if a.Desc {
+		return a.By.of(a.Changes[i]) > a.By.of(a.Changes[j])
+	}
+	return a.By.of(a.Changes[i]) < a.By.of(a.Changes[j])
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Pair each card and set's price on the first and third days
+func TestNameTimeProjectionPriceChanges(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	db := NewPriceDB()
+	db.Push(tuples[:8])
+	proj := NameTimeProjectionFromPriceDB(db)
+	proj.Append(tuples[8:])
+
+	changes := proj.PriceChanges(tuples[0].Time, tuples[8].Time)
+
+	// Windswept Heath's foil has no first day price
+	if len(changes) != 4 {
+		t.Fatalf("found %v changes, expected 4: %v", len(changes), changes)
+	}
+
+	byKey := make(map[NameSet]PriceChange)
+	for _, change := range changes {
+		byKey[NameSet{change.Name, change.Set}] = change
+	}
+
+	heath := byKey[NameSet{"Windswept Heath", "Onslaught"}]
+	if heath.From != 9000 || heath.To != 9400 || heath.Absolute != 400 {
+		t.Fatalf("unexpected change %v", heath)
+	}
+	if heath.Percent < 4.44 || heath.Percent > 4.45 {
+		t.Fatalf("unexpected percentage %v", heath.Percent)
+	}
+
+	// The foil was last seen on the second day
+	foil := byKey[NameSet{"Griselbrand", "Avacyn Restored Foil"}]
+	if foil.To != 5523 || !foil.ToTime.Equal(tuples[7].Time) {
+		t.Fatalf("unexpected change %v", foil)
+	}
+}
+
+// Top movers are ordered by the size of their move either way
+func TestNameTimeProjectionTopMovers(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	tuples := syntheticPriceTuples()
+	movers := proj.TopMovers(tuples[0].Time, tuples[8].Time.Add(time.Hour), 2)
+	if len(movers) != 2 {
+		t.Fatalf("found %v movers, expected 2", len(movers))
+	}
+
+	// Foil +6.2%, then Griselbrand +4.8%, ahead of Heath's +4.4%
+	if movers[0].Set != "Avacyn Restored Foil" || movers[1].Set != "Avacyn Restored" {
+		t.Fatalf("unexpected movers %v", movers)
+	}
+
+	SortPriceChanges(movers, ByAbsolute, false)
+	if movers[0].Absolute > movers[1].Absolute {
+		t.Fatalf("not ascending absolute order %v", movers)
+	}
+}
+
+// Consecutive changes stay within each set
+func TestNameTimeProjectionConsecutiveChanges(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	changes := proj.ConsecutiveChanges("Griselbrand")
+	if len(changes) != 3 {
+		t.Fatalf("found %v changes, expected 3: %v", len(changes), changes)
+	}
+
+	expected := []int64{-100, 323, 200}
+	for i, change := range changes {
+		if change.Absolute != expected[i] {
+			t.Fatalf("unexpected changes %v", changes)
+		}
+	}
+}
@@ -0,0 +1,795 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// How a projection stores one of its columns
+type Encoding uint32
+
+const (
+	// Let the projection decide based on its sort keys
+	EncodingDefault Encoding = iota
+	EncodingPlain
+	EncodingRLE
+)
+
+// Storage for strings, implemented by both FiniteString32Column
+// and RLEFiniteString32Column
+type StringColumn interface {
+	Push(values []string)
+	PushCodes(codes []uint32)
+	Access(index int) string
+	AccessCode(index int) uint32
+	Set(index int, value string)
+	Dictionary() *Dictionary
+	Release()
+	Length() int
+	Equal(value string) BoolColumn
+	Within(values []string) BoolColumn
+}
+
+// Storage for uint32s, implemented by both UInt32Column
+// and RLEUInt32Column
+type IntegerColumn interface {
+	Push(values []uint32)
+	Access(index int) uint32
+	Set(index int, value uint32)
+	Length() int
+	Sum() uint64
+	Equal(value uint32) BoolColumn
+	Less(value uint32) BoolColumn
+	More(value uint32) BoolColumn
+}
+
+// Declares a projection: the order its rows are sorted in
+// and how each of its columns is stored
+type ProjectionSpec struct {
+	Name string
+
+	SortKeys []SortKey
+
+	// Explicit encodings per field
+	//
+	// Fields not present use EncodingDefault, which run length
+	// encodes any sort key column as sorting groups equal values
+	// into long runs. TimeColumn has no RLE flavour, so times
+	// are always stored plainly.
+	Encodings map[PriceField]Encoding
+}
+
+// Common projections
+var (
+	NameTimeSpec = ProjectionSpec{
+		Name:     "name_time",
+		SortKeys: []SortKey{{Field: FieldName}, {Field: FieldTime}},
+	}
+	SetNameTimeSpec = ProjectionSpec{
+		Name: "set_name_time",
+		SortKeys: []SortKey{
+			{Field: FieldSet}, {Field: FieldName}, {Field: FieldTime},
+		},
+	}
+	TimeSpec = ProjectionSpec{
+		Name:     "time",
+		SortKeys: []SortKey{{Field: FieldTime}},
+	}
+	PriceSpec = ProjectionSpec{
+		Name:     "price",
+		SortKeys: []SortKey{{Field: FieldPrice}},
+	}
+)
+
+// Determine the encoding a field is stored with
+func (spec ProjectionSpec) EncodingOf(field PriceField) Encoding {
+	if field == FieldTime {
+		return EncodingPlain
+	}
+
+	if encoding := spec.Encodings[field]; encoding != EncodingDefault {
+		return encoding
+	}
+
+	for _, k := range spec.SortKeys {
+		if k.Field == field {
+			return EncodingRLE
+		}
+	}
+
+	return EncodingPlain
+}
+
+// A copy of the PriceDB's contents sorted according to a spec
+//
+// Columns are stored with whatever encoding the spec declares,
+// so sorted leading columns are typically run length encoded.
+//
+// The columns form the read optimized main store. Appended rows
+// land in a small, sorted delta which is periodically merged
+// into the main store. Positions past the end of the main store
+// address the delta.
+//
+// Predicates on the columns themselves only see the main store,
+// queries must go through the projection's own predicates, such
+// as NameEqual, to see rows not yet merged.
+//
+// Rows removed from the main store are only marked, keeping
+// positions stable, until the next merge drops them.
+type Projection struct {
+	Spec ProjectionSpec
+
+	Names StringColumn
+	Sets  StringColumn
+
+	Prices IntegerColumn
+
+	Times TimeColumn
+
+	// Write optimized store, kept sorted by the spec's keys
+	delta []PriceTuple
+
+	// Rows of the main store removed since the last merge,
+	// possibly nil
+	removed *DeleteVector
+
+	// Set while a snapshot shares the main store's columns,
+	// which then must not be written in place
+	shared bool
+
+	// Number of delta rows which triggers a merge, falling
+	// back to DefaultMergeThreshold when zero
+	MergeThreshold int
+}
+
+// Number of appended rows a projection buffers before
+// merging them into its main store
+const DefaultMergeThreshold = 1 << 16
+
+// Create an empty projection sized for capacity rows
+//
+// Capacity is a sizing hint for run length encoded columns,
+// which grow past it as required.
+func NewProjection(spec ProjectionSpec, capacity int) Projection {
+	return NewProjectionSharing(spec, capacity, NewDictionary(), NewDictionary())
+}
+
+// Create an empty projection sized for capacity rows which
+// encodes names and sets with existing dictionaries
+func NewProjectionSharing(spec ProjectionSpec, capacity int,
+	names, sets *Dictionary) Projection {
+
+	return Projection{
+		Spec:   spec,
+		Names:  newStringColumn(spec.EncodingOf(FieldName), capacity, names),
+		Sets:   newStringColumn(spec.EncodingOf(FieldSet), capacity, sets),
+		Prices: newIntegerColumn(spec.EncodingOf(FieldPrice), capacity),
+		Times:  NewTimeColumn(),
+	}
+}
+
+func newStringColumn(encoding Encoding, capacity int,
+	dictionary *Dictionary) StringColumn {
+
+	if encoding == EncodingRLE {
+		col := NewRLEFiniteString32ColumnSharing(dictionary, capacity)
+		return &col
+	}
+
+	col := NewFiniteString32ColumnSharing(dictionary)
+	return &col
+}
+
+func newIntegerColumn(encoding Encoding, capacity int) IntegerColumn {
+	if encoding == EncodingRLE {
+		col := NewRLEUInt32Column(capacity)
+		return &col
+	}
+
+	col := NewUInt32Column()
+	return &col
+}
+
+// Generate a projection from a fully filled PriceDB
+//
+// Rather than materializing and sorting tuples, we sort a
+// permutation of row positions using only integer encodings of
+// the sort keys then gather each column through it in turn.
+//
+// The projection shares the database's dictionaries, so its
+// codes can be compared with the database's directly.
+func ProjectionFromPriceDB(db PriceDB, spec ProjectionSpec) Projection {
+
+	// Determine live length of database, deleted rows
+	// are left out entirely
+	length := db.LiveLength()
+
+	proj := NewProjectionSharing(spec, length,
+		db.Names.Dictionary(), db.Sets.Dictionary())
+
+	permutation := make([]int, 0, length)
+	for i := 0; i < db.Prices.Length(); i++ {
+		if !db.deleted.Deleted(i) {
+			permutation = append(permutation, i)
+		}
+	}
+
+	keys := make([]encodedKey, len(spec.SortKeys))
+	for i, k := range spec.SortKeys {
+		keys[i] = encodeKey(db, k)
+	}
+	sort.Sort(PermutationOrder{permutation, keys})
+
+	// Gather one column at a time so only a single
+	// column's worth of values is ever in flight
+	// Dictionaries are shared so codes move across untouched
+	names := make([]uint32, length)
+	for i, p := range permutation {
+		names[i] = db.Names.AccessCode(p)
+	}
+	proj.Names.PushCodes(names)
+
+	sets := make([]uint32, length)
+	for i, p := range permutation {
+		sets[i] = db.Sets.AccessCode(p)
+	}
+	proj.Sets.PushCodes(sets)
+
+	prices := make([]uint32, length)
+	for i, p := range permutation {
+		prices[i] = db.Prices.Access(p)
+	}
+	proj.Prices.Push(prices)
+
+	times := make([]time.Time, length)
+	for i, p := range permutation {
+		times[i] = db.Times.Access(p)
+	}
+	proj.Times.Push(times)
+
+	return proj
+}
+
+// A sort key reduced to integers which order
+// identically to the values they encode
+//
+// Strings are replaced by the rank of their dictionary code in
+// sorted order, looked up once per row rather than per compare.
+// Times become nanoseconds since epoch. Descending keys have
+// their bits inverted so every key compares ascending.
+type encodedKey struct {
+	// Ranks for strings, raw values for prices
+	values []uint32
+
+	// Nanoseconds since epoch, for times
+	nanos []int64
+}
+
+func encodeKey(db PriceDB, k SortKey) encodedKey {
+	var encoded encodedKey
+
+	switch k.Field {
+	case FieldName, FieldSet:
+		column := &db.Names
+		if k.Field == FieldSet {
+			column = &db.Sets
+		}
+		ranks := column.Dictionary().ranks()
+		encoded.values = column.contents.values()
+		for i, code := range encoded.values {
+			encoded.values[i] = ranks[code]
+		}
+	case FieldPrice:
+		encoded.values = db.Prices.values()
+	case FieldTime:
+		encoded.nanos = make([]int64, 0, db.Times.Length())
+		for g := 0; g < rowGroups(db.Times.Length()); g++ {
+			for _, v := range db.Times.Chunk(g) {
+				encoded.nanos = append(encoded.nanos, v.UnixNano())
+			}
+		}
+	}
+
+	if k.Desc {
+		for i, v := range encoded.values {
+			encoded.values[i] = ^v
+		}
+		for i, v := range encoded.nanos {
+			encoded.nanos[i] = ^v
+		}
+	}
+
+	return encoded
+}
+
+// Compare the rows at positions i and j on this key
+func (k encodedKey) compare(i, j int) int {
+	if k.values != nil {
+		return compareUInt32(k.values[i], k.values[j])
+	}
+	return compareInt64(k.nanos[i], k.nanos[j])
+}
+
+// Row positions ordered by a list of encoded keys
+//
+// Rows tying on every key fall back to their position,
+// giving the ordering of a stable sort without its cost.
+type PermutationOrder struct {
+	Positions []int
+	keys      []encodedKey
+}
+
+func (a PermutationOrder) Len() int {
+	return len(a.Positions)
+}
+func (a PermutationOrder) Swap(i, j int) {
+	a.Positions[i], a.Positions[j] = a.Positions[j], a.Positions[i]
+}
+func (a PermutationOrder) Less(i, j int) bool {
+	first := a.Positions[i]
+	second := a.Positions[j]
+	for _, k := range a.keys {
+		if result := k.compare(first, second); result != 0 {
+			return result < 0
+		}
+	}
+
+	return first < second
+}
+
+func (proj *Projection) Push(values []PriceTuple) {
+	names := make([]string, len(values))
+	sets := make([]string, len(values))
+	prices := make([]uint32, len(values))
+	times := make([]time.Time, len(values))
+	for i, p := range values {
+		names[i] = p.Name
+		sets[i] = p.Set
+		prices[i] = p.Price
+		times[i] = p.Time
+	}
+	proj.Names.Push(names)
+	proj.Sets.Push(sets)
+	proj.Prices.Push(prices)
+	proj.Times.Push(times)
+}
+
+// Determine the number of rows in this projection, including
+// those not yet merged into the main store
+func (proj *Projection) Length() int {
+	return proj.MainLength() + len(proj.delta)
+}
+
+// Determine the number of rows in this projection which
+// have not been removed
+func (proj *Projection) LiveLength() int {
+	return proj.Length() - proj.removed.Count()
+}
+
+// Determine the number of rows in the main store
+//
+// Positions at or past this address the delta.
+func (proj *Projection) MainLength() int {
+	return proj.Prices.Length()
+}
+
+// Determine the number of rows waiting to be merged
+func (proj *Projection) DeltaLength() int {
+	return len(proj.delta)
+}
+
+// Access the tuple stored at the named position
+//
+// This performs no range checking so an invalid
+// index will cause a panic. The caller is responsible
+// for ensuring index is within bounds
+func (proj *Projection) Access(index int) PriceTuple {
+	main := proj.MainLength()
+	if index >= main {
+		return proj.delta[index-main]
+	}
+
+	return PriceTuple{
+		Name:  proj.Names.Access(index),
+		Set:   proj.Sets.Access(index),
+		Price: proj.Prices.Access(index),
+		Time:  proj.Times.Access(index),
+	}
+}
+
+// Access individual fields at a position of either store,
+// avoiding materializing a full tuple when only one is needed
+func (proj *Projection) timeAt(index int) time.Time {
+	if main := proj.MainLength(); index >= main {
+		return proj.delta[index-main].Time
+	}
+	return proj.Times.Access(index)
+}
+func (proj *Projection) priceAt(index int) uint32 {
+	if main := proj.MainLength(); index >= main {
+		return proj.delta[index-main].Price
+	}
+	return proj.Prices.Access(index)
+}
+func (proj *Projection) setAt(index int) string {
+	if main := proj.MainLength(); index >= main {
+		return proj.delta[index-main].Set
+	}
+	return proj.Sets.Access(index)
+}
+
+// Add a batch of new rows to the projection
+//
+// Rows are sorted and merged into the delta, which is cheap
+// as the delta is small. Once the delta reaches the merge
+// threshold it is folded into the main store.
+func (proj *Projection) Append(values []PriceTuple) {
+	batch := make([]PriceTuple, len(values))
+	copy(batch, values)
+	sort.Stable(KeyOrderedTuples{batch, proj.Spec.SortKeys})
+
+	// Merge the two sorted runs, delta first on ties
+	// so earlier appends keep their relative order
+	merged := make([]PriceTuple, 0, len(proj.delta)+len(batch))
+	i, j := 0, 0
+	for i < len(proj.delta) && j < len(batch) {
+		if compareTuplesByKeys(proj.Spec.SortKeys, batch[j], proj.delta[i]) < 0 {
+			merged = append(merged, batch[j])
+			j++
+		} else {
+			merged = append(merged, proj.delta[i])
+			i++
+		}
+	}
+	merged = append(merged, proj.delta[i:]...)
+	merged = append(merged, batch[j:]...)
+	proj.delta = merged
+
+	if len(proj.delta) >= proj.mergeThreshold() {
+		proj.Merge()
+	}
+}
+
+// Determine the number of delta or removed rows
+// which triggers a merge
+func (proj *Projection) mergeThreshold() int {
+	if proj.MergeThreshold == 0 {
+		return DefaultMergeThreshold
+	}
+	return proj.MergeThreshold
+}
+
+// Locate a live row holding exactly a tuple in either store
+//
+// The tuple's sort keys narrow the search to its equals
+// by binary search.
+func (proj *Projection) find(t PriceTuple) (int, bool) {
+	values := make([]interface{}, len(proj.Spec.SortKeys))
+	for i, k := range proj.Spec.SortKeys {
+		values[i] = fieldValue(k.Field, t)
+	}
+
+	start, end := proj.KeyRange(values...)
+	for i := start; i < end; i++ {
+		if !proj.removed.Deleted(i) && sameTuple(proj.Access(i), t) {
+			return i, true
+		}
+	}
+
+	main := proj.MainLength()
+	start, end = proj.DeltaKeyRange(values...)
+	for i := start; i < end; i++ {
+		if sameTuple(proj.delta[i], t) {
+			return main + i, true
+		}
+	}
+
+	return 0, false
+}
+
+// Remove a row holding exactly a tuple, reporting whether
+// one was found
+//
+// Rows of the main store are marked until the next merge, which
+// happens once as many are marked as the merge threshold. Rows
+// of the delta are dropped immediately.
+func (proj *Projection) Remove(t PriceTuple) bool {
+	p, found := proj.find(t)
+	if found {
+		proj.removeAt(p)
+	}
+
+	return found
+}
+
+// Remove the row at a position of either store
+func (proj *Projection) removeAt(p int) {
+	main := proj.MainLength()
+	if p >= main {
+		// Snapshots may share the delta, so it is
+		// replaced rather than modified
+		d := p - main
+		delta := make([]PriceTuple, 0, len(proj.delta)-1)
+		delta = append(delta, proj.delta[:d]...)
+		proj.delta = append(delta, proj.delta[d+1:]...)
+		return
+	}
+
+	if proj.removed == nil {
+		proj.removed = NewDeleteVector()
+	}
+	proj.removed.Delete(p)
+	if proj.removed.Count() >= proj.mergeThreshold() {
+		proj.Merge()
+	}
+}
+
+// Overwrite a row holding exactly a tuple with another,
+// reporting whether one was found
+//
+// Rows keeping their sort keys are overwritten where they are.
+// Rows whose sort keys change would fall out of order, so are
+// removed and appended again through the delta.
+func (proj *Projection) Replace(before, after PriceTuple) bool {
+	found, moved := proj.replace(before, after)
+	if moved {
+		proj.Append([]PriceTuple{after})
+	}
+
+	return found
+}
+
+// Overwrite a row in place where possible, otherwise remove
+// it and report that after must be appended
+func (proj *Projection) replace(before, after PriceTuple) (found, moved bool) {
+	p, found := proj.find(before)
+	if !found {
+		return false, false
+	}
+
+	main := proj.MainLength()
+	inPlace := compareTuplesByKeys(proj.Spec.SortKeys, before, after) == 0
+	switch {
+	case inPlace && p >= main:
+		// Snapshots may share the delta
+		delta := append([]PriceTuple(nil), proj.delta...)
+		delta[p-main] = after
+		proj.delta = delta
+	case inPlace && !proj.shared:
+		proj.set(p, before, after)
+	default:
+		proj.removeAt(p)
+		return true, true
+	}
+
+	return true, false
+}
+
+// Overwrite the fields of a main store row which differ
+func (proj *Projection) set(p int, before, after PriceTuple) {
+	if after.Name != before.Name {
+		proj.Names.Set(p, after.Name)
+	}
+	if after.Set != before.Set {
+		proj.Sets.Set(p, after.Set)
+	}
+	if after.Price != before.Price {
+		proj.Prices.Set(p, after.Price)
+	}
+	if !after.Time.Equal(before.Time) {
+		proj.Times.Set(p, after.Time)
+	}
+}
+
+// Find the last row of the main store in [start, end)
+// which has not been removed
+func (proj *Projection) lastLive(start, end int) (int, bool) {
+	for i := end - 1; i >= start; i-- {
+		if !proj.removed.Deleted(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Fold the delta into the main store, dropping removed rows
+//
+// The main store is rewritten from a single linear merge of
+// the two sorted stores, so this costs the full projection.
+func (proj *Projection) Merge() {
+	if len(proj.delta) == 0 && proj.removed.Count() == 0 {
+		return
+	}
+
+	main := proj.MainLength()
+	merged := NewProjectionSharing(proj.Spec, proj.Length(),
+		proj.Names.Dictionary(), proj.Sets.Dictionary())
+
+	batch := make([]PriceTuple, 0, 4096)
+	i, j := 0, 0
+	for {
+		for i < main && proj.removed.Deleted(i) {
+			i++
+		}
+		if i >= main && j >= len(proj.delta) {
+			break
+		}
+
+		var next PriceTuple
+		if i < main {
+			next = proj.Access(i)
+		}
+
+		// Main store wins ties, it was ingested first
+		if i >= main || (j < len(proj.delta) &&
+			compareTuplesByKeys(proj.Spec.SortKeys, proj.delta[j], next) < 0) {
+			next = proj.delta[j]
+			j++
+		} else {
+			i++
+		}
+
+		batch = append(batch, next)
+		if len(batch) >= 4096 {
+			merged.Push(batch)
+			batch = batch[:0]
+		}
+	}
+	merged.Push(batch)
+
+	// Hand our references to the replaced columns over
+	proj.Names.Release()
+	proj.Sets.Release()
+
+	proj.Names = merged.Names
+	proj.Sets = merged.Sets
+	proj.Prices = merged.Prices
+	proj.Times = merged.Times
+	proj.delta = nil
+	proj.removed = nil
+	proj.shared = false
+}
+
+// Extend a predicate's result over the main store with
+// every delta row passing test
+//
+// The delta's bits are packed into words and appended
+// whole, following on from the main store's positions.
+func (proj *Projection) withDelta(results BoolColumn,
+	test func(p PriceTuple) bool) BoolColumn {
+
+	proj.removed.mask(results)
+
+	n := len(proj.delta)
+	words := make([]uint64, (n+63)/64)
+	packWords(words, n, func(i int) bool { return test(proj.delta[i]) })
+	results.PushWords(words, n)
+
+	return results
+}
+
+// Determine all rows, of both stores, with a name equal to
+// a provided value and return them positionally as a BoolColumn
+func (proj *Projection) NameEqual(value string) BoolColumn {
+	return proj.withDelta(proj.Names.Equal(value), func(p PriceTuple) bool {
+		return p.Name == value
+	})
+}
+
+// Determine all rows, of both stores, with a name equal to a
+// member of the provided values
+func (proj *Projection) NameWithin(values []string) BoolColumn {
+	return proj.withDelta(proj.Names.Within(values), func(p PriceTuple) bool {
+		return stringWithin(p.Name, values)
+	})
+}
+
+// Determine all rows, of both stores, with a set equal to
+// a provided value
+func (proj *Projection) SetEqual(value string) BoolColumn {
+	return proj.withDelta(proj.Sets.Equal(value), func(p PriceTuple) bool {
+		return p.Set == value
+	})
+}
+
+// Determine all rows, of both stores, with a set equal to a
+// member of the provided values
+func (proj *Projection) SetWithin(values []string) BoolColumn {
+	return proj.withDelta(proj.Sets.Within(values), func(p PriceTuple) bool {
+		return stringWithin(p.Set, values)
+	})
+}
+
+// Determine all rows, of both stores, with a price equal to
+// a provided value
+func (proj *Projection) PriceEqual(value uint32) BoolColumn {
+	return proj.withDelta(proj.Prices.Equal(value), func(p PriceTuple) bool {
+		return p.Price == value
+	})
+}
+
+// Determine all rows, of both stores, with a price less
+// than a provided value
+func (proj *Projection) PriceLess(value uint32) BoolColumn {
+	return proj.withDelta(proj.Prices.Less(value), func(p PriceTuple) bool {
+		return p.Price < value
+	})
+}
+
+// Determine all rows, of both stores, with a price greater
+// than or equal to a provided value
+func (proj *Projection) PriceMore(value uint32) BoolColumn {
+	return proj.withDelta(proj.Prices.More(value), func(p PriceTuple) bool {
+		return p.Price >= value
+	})
+}
+
+// Determine if a string is a member of the provided values
+func stringWithin(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Materialize all PriceTuples that are truthy from
+// the provided BoolColumn
+//
+// The assumption is that the provided BoolColumn is
+// the result of a predicate executed on this projection.
+// As a result, we do no range checking.
+//
+// Passing a BoolColumn that was not created by this
+// projection instance has no guarantees regarding safety.
+func (proj *Projection) MaterializeFromBools(b BoolColumn) []PriceTuple {
+
+	// Grab all indices which this column is truthy
+	positions := b.TruthyIndices()
+
+	// Positions past the main store come straight from the delta
+	main := proj.MainLength()
+	for len(positions) > 0 && positions[len(positions)-1] >= main {
+		positions = positions[:len(positions)-1]
+	}
+	if proj.removed.Count() > 0 {
+		live := positions[:0]
+		for _, p := range positions {
+			if !proj.removed.Deleted(p) {
+				live = append(live, p)
+			}
+		}
+		positions = live
+	}
+	var fromDelta []PriceTuple
+	for p, ok := b.NextTruthy(main); ok; p, ok = b.NextTruthy(p + 1) {
+		fromDelta = append(fromDelta, proj.delta[p-main])
+	}
+
+	// Keep columns separate for as long as possible
+	names := make([]string, len(positions))
+	sets := make([]string, len(positions))
+	prices := make([]uint32, len(positions))
+	times := make([]time.Time, len(positions))
+	for i, p := range positions {
+		names[i] = proj.Names.Access(p)
+		sets[i] = proj.Sets.Access(p)
+		prices[i] = proj.Prices.Access(p)
+		times[i] = proj.Times.Access(p)
+	}
+
+	// Stitch tuples back together into fancy structs
+	tuples := make([]PriceTuple, len(positions))
+	for i := range positions {
+		tuples[i] = PriceTuple{
+			Name:  names[i],
+			Set:   sets[i],
+			Price: prices[i],
+			Time:  times[i],
+		}
+	}
+
+	return append(tuples, fromDelta...)
+
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Build a tuple holding only a single field's value
+//
+// Values must be a string for names and sets, a uint32 for
+// prices and a time.Time for times.
+func probeTuple(field PriceField, value interface{}) PriceTuple {
+	var probe PriceTuple
+	switch field {
+	case FieldName:
+		probe.Name = value.(string)
+	case FieldSet:
+		probe.Set = value.(string)
+	case FieldPrice:
+		probe.Price = value.(uint32)
+	case FieldTime:
+		probe.Time = value.(time.Time)
+	}
+	return probe
+}
+
+// Extract a single field's value from a tuple, the
+// inverse of probeTuple
+func fieldValue(field PriceField, t PriceTuple) interface{} {
+	switch field {
+	case FieldName:
+		return t.Name
+	case FieldSet:
+		return t.Set
+	case FieldPrice:
+		return t.Price
+	}
+	return t.Time
+}
+
+// Determine if two tuples hold the same values, comparing
+// times as instants
+func sameTuple(a, b PriceTuple) bool {
+	return a.Name == b.Name && a.Set == b.Set &&
+		a.Price == b.Price && a.Time.Equal(b.Time)
+}
+
+// Access a single field of the main store as a tuple
+// holding only that field
+func (proj *Projection) fieldAt(field PriceField, index int) PriceTuple {
+	var row PriceTuple
+	switch field {
+	case FieldName:
+		row.Name = proj.Names.Access(index)
+	case FieldSet:
+		row.Set = proj.Sets.Access(index)
+	case FieldPrice:
+		row.Price = proj.Prices.Access(index)
+	case FieldTime:
+		row.Time = proj.Times.Access(index)
+	}
+	return row
+}
+
+// Determine the run of the main store containing the named
+// index, if the field is run length encoded
+func (proj *Projection) runAt(field PriceField, index int) (start, end int, ok bool) {
+	var column interface {
+		RunAt(int) (int, int)
+	}
+
+	switch field {
+	case FieldName:
+		column, ok = proj.Names.(*RLEFiniteString32Column)
+	case FieldSet:
+		column, ok = proj.Sets.(*RLEFiniteString32Column)
+	case FieldPrice:
+		column, ok = proj.Prices.(*RLEUInt32Column)
+	}
+	if !ok {
+		return 0, 0, false
+	}
+
+	start, end = column.RunAt(index)
+	return start, end, true
+}
+
+// Narrow [start, end) of a sorted sequence to the rows
+// whose key matches the probe's
+func narrowRange(start, end int, at func(int) PriceTuple,
+	k SortKey, probe PriceTuple) (int, int) {
+
+	// First row not sorting before the probe
+	lower := start + sort.Search(end-start, func(i int) bool {
+		return k.compareTuples(at(start+i), probe) >= 0
+	})
+
+	// First row sorting after the probe
+	upper := lower + sort.Search(end-lower, func(i int) bool {
+		return k.compareTuples(at(lower+i), probe) > 0
+	})
+
+	return lower, upper
+}
+
+// Determine the rows of the main store matching values for
+// the leading sort keys, in order, by binary search
+//
+// KeyRange("Griselbrand") on a NameTimeProjection gives every
+// row for that card. Run length encoded keys give the end of
+// a range directly from the run found by the search.
+func (proj *Projection) KeyRange(values ...interface{}) (start, end int) {
+	start, end = 0, proj.MainLength()
+
+	for depth, value := range values {
+		k := proj.Spec.SortKeys[depth]
+		probe := probeTuple(k.Field, value)
+		at := func(i int) PriceTuple {
+			return proj.fieldAt(k.Field, i)
+		}
+
+		lower := start + sort.Search(end-start, func(i int) bool {
+			return k.compareTuples(at(start+i), probe) >= 0
+		})
+
+		// A matching run ends the range without a second search
+		if lower < end && k.compareTuples(at(lower), probe) == 0 {
+			if _, runEnd, ok := proj.runAt(k.Field, lower); ok {
+				if runEnd > end {
+					runEnd = end
+				}
+				start, end = lower, runEnd
+				continue
+			}
+		}
+
+		start, end = narrowRange(lower, end, at, k, probe)
+	}
+
+	return start, end
+}
+
+// Determine the rows of the delta matching values for the
+// leading sort keys, in order, by binary search
+//
+// Positions are relative to the start of the delta.
+func (proj *Projection) DeltaKeyRange(values ...interface{}) (start, end int) {
+	start, end = 0, len(proj.delta)
+	at := func(i int) PriceTuple {
+		return proj.delta[i]
+	}
+
+	for depth, value := range values {
+		k := proj.Spec.SortKeys[depth]
+		start, end = narrowRange(start, end, at, k, probeTuple(k.Field, value))
+	}
+
+	return start, end
+}
+
+// Narrow [start, end) of the main store to rows with times
+// in [from, to) by binary search
+//
+// Times must be sorted within the range, as they are once
+// every sort key preceding time has been fixed by KeyRange.
+func (proj *Projection) TimeRange(start, end int, from, to time.Time) (int, int) {
+	return timeRange(start, end, proj.Times.Access, from, to)
+}
+
+// Narrow [start, end) of the delta to rows with times
+// in [from, to) by binary search
+func (proj *Projection) DeltaTimeRange(start, end int, from, to time.Time) (int, int) {
+	at := func(i int) time.Time {
+		return proj.delta[i].Time
+	}
+	return timeRange(start, end, at, from, to)
+}
+
+func timeRange(start, end int, at func(int) time.Time,
+	from, to time.Time) (int, int) {
+
+	lower := start + sort.Search(end-start, func(i int) bool {
+		return !at(start + i).Before(from)
+	})
+	upper := lower + sort.Search(end-lower, func(i int) bool {
+		return !at(lower + i).Before(to)
+	})
+
+	return lower, upper
+}
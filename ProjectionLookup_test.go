@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Binary search ranges must hold exactly the rows a
+// full scan finds, for both encodings of the leading key
+func TestProjectionKeyRange(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	plain := NameTimeSpec
+	plain.Encodings = map[PriceField]Encoding{FieldName: EncodingPlain}
+
+	for _, spec := range []ProjectionSpec{NameTimeSpec, plain} {
+		proj := ProjectionFromPriceDB(db, spec)
+
+		names := []string{"Avacyn, Angel of Hope", "Griselbrand",
+			"Windswept Heath", "Aaa", "Zzz"}
+		for _, name := range names {
+			start, end := proj.KeyRange(name)
+
+			query := proj.Names.Equal(name)
+			truthy := query.TruthyIndices()
+			if len(truthy) != end-start {
+				t.Fatalf("range [%v, %v) for %v, scan found %v",
+					start, end, name, truthy)
+			}
+			for i, index := range truthy {
+				if index != start+i {
+					t.Fatalf("range [%v, %v) for %v, scan found %v",
+						start, end, name, truthy)
+				}
+			}
+		}
+	}
+}
+
+// Ranges narrow through each leading sort key in turn
+func TestProjectionKeyRangeMultiKey(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := ProjectionFromPriceDB(db, SetNameTimeSpec)
+
+	start, end := proj.KeyRange("Avacyn Restored", "Griselbrand")
+	if end-start != 3 {
+		t.Fatalf("found %v rows, expected 3", end-start)
+	}
+	for i := start; i < end; i++ {
+		tuple := proj.Access(i)
+		if tuple.Set != "Avacyn Restored" || tuple.Name != "Griselbrand" {
+			t.Fatalf("unexpected row in range %v", tuple)
+		}
+	}
+
+	start, end = proj.KeyRange("Onslaught Foil", "Griselbrand")
+	if start != end {
+		t.Fatalf("found %v rows for missing key", end-start)
+	}
+}
+
+// History covers [from, to) across both stores in time order
+func TestNameTimeProjectionHistory(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	db := NewPriceDB()
+	db.Push(tuples[:8])
+	proj := NameTimeProjectionFromPriceDB(db)
+	proj.Append(tuples[8:])
+
+	from := time.Date(2016, time.March, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2016, time.March, 4, 0, 0, 0, 0, time.UTC)
+	history := proj.History("Griselbrand", from, to)
+
+	expected := []uint32{2000, 5523, 2200}
+	if len(history) != len(expected) {
+		t.Fatalf("unexpected history %v", history)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i-1].Time.After(history[i].Time) {
+			t.Fatalf("history not in time order %v", history)
+		}
+	}
+	if history[2].Price != expected[2] {
+		t.Fatalf("history missed the delta %v", history)
+	}
+
+	// Full history agrees with a range covering every price
+	var epoch time.Time
+	full := proj.FullHistory("Griselbrand")
+	bounded := proj.History("Griselbrand", epoch, to)
+	if len(full) != len(bounded) || len(full) == len(history) {
+		t.Fatalf("unexpected full history %v", full)
+	}
+	for i := range bounded {
+		if full[i] != bounded[i] {
+			t.Fatalf("full history differs at %v: %v != %v",
+				i, full[i], bounded[i])
+		}
+	}
+
+	// Latest agrees with a scan of the projection
+	latest := proj.MaterializeFromBools(proj.Latest("Windswept Heath"))
+	if len(latest) != 2 {
+		t.Fatalf("unexpected latest %v", latest)
+	}
+	for _, tuple := range latest {
+		if !tuple.Time.Equal(tuples[8].Time) {
+			t.Fatalf("unexpected latest %v", latest)
+		}
+	}
+	missing := proj.Latest("Zzz")
+	if len(missing.TruthyIndices()) != 0 {
+		t.Fatalf("found latest prices for a missing card")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+)
+
+// Declare each of the common projections over the synthetic
+// database and check they hold every row in sort order
+func TestProjectionFromPriceDBSorted(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	specs := []ProjectionSpec{NameTimeSpec, SetNameTimeSpec, TimeSpec, PriceSpec}
+	for _, spec := range specs {
+		proj := ProjectionFromPriceDB(db, spec)
+
+		if proj.Length() != db.Prices.Length() {
+			t.Fatalf("%v: mismatching projection and db lengths %v != %v",
+				spec.Name, proj.Length(), db.Prices.Length())
+		}
+
+		tuples := proj.MaterializeFromBools(allRows(proj.Length()))
+		for i := 1; i < len(tuples); i++ {
+			if compareTuplesByKeys(spec.SortKeys, tuples[i-1], tuples[i]) > 0 {
+				t.Fatalf("%v: not sorted at index %v: %v then %v",
+					spec.Name, i, tuples[i-1], tuples[i])
+			}
+		}
+
+		if proj.Prices.Sum() != db.Prices.Sum() {
+			t.Fatalf("%v: prices sum differs %v != %v",
+				spec.Name, proj.Prices.Sum(), db.Prices.Sum())
+		}
+	}
+}
+
+// Leading sort columns are run length encoded unless
+// the spec says otherwise
+func TestProjectionEncodings(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	proj := ProjectionFromPriceDB(db, SetNameTimeSpec)
+	if _, ok := proj.Sets.(*RLEFiniteString32Column); !ok {
+		t.Fatalf("sets not run length encoded")
+	}
+	if _, ok := proj.Names.(*RLEFiniteString32Column); !ok {
+		t.Fatalf("names not run length encoded")
+	}
+	if _, ok := proj.Prices.(*UInt32Column); !ok {
+		t.Fatalf("prices unexpectedly run length encoded")
+	}
+
+	spec := PriceSpec
+	spec.Encodings = map[PriceField]Encoding{FieldPrice: EncodingPlain}
+	proj = ProjectionFromPriceDB(db, spec)
+	if _, ok := proj.Prices.(*UInt32Column); !ok {
+		t.Fatalf("explicit plain encoding ignored")
+	}
+
+	// Run length encoded predicates agree with the plain ones
+	query := proj.Names.Equal("Griselbrand")
+	if len(query.TruthyIndices()) != 5 {
+		t.Fatalf("found %v Griselbrand rows, expected 5",
+			len(query.TruthyIndices()))
+	}
+}
+
+// Building through a sorted permutation must agree exactly
+// with stably sorting fully materialized tuples
+func TestProjectionMatchesOrderBy(t *testing.T) {
+	db := setupSyntheticPriceDB()
+
+	specs := []ProjectionSpec{NameTimeSpec, SetNameTimeSpec, TimeSpec, PriceSpec}
+	for _, spec := range specs {
+		proj := ProjectionFromPriceDB(db, spec)
+
+		projected := proj.MaterializeFromBools(allRows(proj.Length()))
+		sorted := db.OrderBy(allRows(db.Prices.Length()), spec.SortKeys)
+		for i := range sorted {
+			if projected[i] != sorted[i] {
+				t.Fatalf("%v: differs at %v: %v != %v",
+					spec.Name, i, projected[i], sorted[i])
+			}
+		}
+	}
+}
+
+// Rows appended after the build are visible immediately
+// and land in sort order once merged
+func TestProjectionAppendAndMerge(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	// Build from the first two days only
+	db := NewPriceDB()
+	db.Push(tuples[:8])
+	proj := NameTimeProjectionFromPriceDB(db)
+	proj.MergeThreshold = 1000
+
+	// Ingest the third day in two batches
+	proj.Append(tuples[8:10])
+	proj.Append(tuples[10:])
+
+	full := setupSyntheticPriceDB()
+	if proj.Length() != full.Prices.Length() || proj.DeltaLength() != 4 {
+		t.Fatalf("unexpected lengths %v with %v in delta",
+			proj.Length(), proj.DeltaLength())
+	}
+
+	// Latest must see the appended rows
+	latest := proj.MaterializeFromBools(proj.Latest("Griselbrand"))
+	if len(latest) != 1 || latest[0].Price != 2200 {
+		t.Fatalf("latest ignored the delta, got %v", latest)
+	}
+	latest = proj.MaterializeFromBools(proj.Latest("Windswept Heath"))
+	if len(latest) != 2 {
+		t.Fatalf("latest ignored the delta, got %v", latest)
+	}
+
+	// Predicates must see the appended rows
+	predicates := []struct {
+		name           string
+		computed, full BoolColumn
+	}{
+		{"name equal", proj.NameEqual("Griselbrand"), full.Names.Equal("Griselbrand")},
+		{"set within", proj.SetWithin([]string{"Avacyn Restored", "Onslaught"}),
+			full.Sets.Within([]string{"Avacyn Restored", "Onslaught"})},
+		{"price less", proj.PriceLess(2000), full.Prices.Less(2000)},
+		{"price more", proj.PriceMore(2000), full.Prices.More(2000)},
+	}
+	for _, p := range predicates {
+		computed := proj.MaterializeFromBools(p.computed)
+		expected := full.MaterializeFromBools(p.full)
+		if len(computed) != len(expected) {
+			t.Fatalf("%v found %v rows, expected %v", p.name,
+				len(computed), len(expected))
+		}
+	}
+
+	// Once merged, the projection matches one built from scratch
+	proj.Merge()
+	if proj.DeltaLength() != 0 {
+		t.Fatalf("merge left %v rows in the delta", proj.DeltaLength())
+	}
+	rebuilt := NameTimeProjectionFromPriceDB(full)
+	merged := proj.MaterializeFromBools(allRows(proj.Length()))
+	expected := rebuilt.MaterializeFromBools(allRows(rebuilt.Length()))
+	for i := range expected {
+		if merged[i] != expected[i] {
+			t.Fatalf("merged differs at %v: %v != %v", i, merged[i], expected[i])
+		}
+	}
+}
+
+// Reaching the merge threshold folds the delta automatically
+func TestProjectionMergeThreshold(t *testing.T) {
+	tuples := syntheticPriceTuples()
+
+	proj := NewProjection(PriceSpec, 0)
+	proj.MergeThreshold = 5
+	for _, tuple := range tuples {
+		proj.Append([]PriceTuple{tuple})
+	}
+
+	if proj.Length() != len(tuples) || proj.DeltaLength() >= 5 {
+		t.Fatalf("unexpected lengths %v with %v in delta",
+			proj.Length(), proj.DeltaLength())
+	}
+
+	all := proj.MaterializeFromBools(allRows(proj.Length()))
+	for i := 1; i < proj.MainLength(); i++ {
+		if all[i-1].Price > all[i].Price {
+			t.Fatalf("main store not sorted at %v", i)
+		}
+	}
+}
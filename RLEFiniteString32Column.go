@@ -8,20 +8,30 @@ type RLEFiniteString32Column struct {
 	// Underlying storage exploits all properties of ints
 	contents RLEUInt32Column
 
-	// Translation and inversion structure
-	// for compressing strings into flat ints
-	translator        map[string]uint32
-	inverter          map[uint32]string
-	translatorCounter uint32
+	// Possibly shared translation of strings into flat ints
+	dictionary *Dictionary
 }
 
 func NewRLEFiniteString32Column() RLEFiniteString32Column {
+	return NewSizedRLEFiniteString32Column(RowGroupSize)
+}
+
+// Create a column sized for capacity values, growing
+// past it as required
+func NewSizedRLEFiniteString32Column(capacity int) RLEFiniteString32Column {
+	return NewRLEFiniteString32ColumnSharing(NewDictionary(), capacity)
+}
+
+// Create a column sized for capacity values, encoding
+// them with an existing dictionary so its codes are comparable
+// with every other column using it
+func NewRLEFiniteString32ColumnSharing(dictionary *Dictionary,
+	capacity int) RLEFiniteString32Column {
+
 	return RLEFiniteString32Column{
-		contents: NewRLEUInt32Column(1000000),
+		contents: NewRLEUInt32Column(capacity),
 
-		translator:        make(map[string]uint32),
-		inverter:          make(map[uint32]string),
-		translatorCounter: 0,
+		dictionary: dictionary.Retain(),
 	}
 }
 
@@ -29,23 +39,18 @@ func (c *RLEFiniteString32Column) Push(values []string) {
 	translated := make([]uint32, len(values))
 
 	for i, v := range values {
-		key, ok := c.translator[v]
-		if !ok {
-			// Increment translator counter
-			c.translatorCounter += 1
-			key = c.translatorCounter
-
-			// Add key to translator and inverter
-			c.translator[v] = key
-			c.inverter[key] = v
-		}
-		translated[i] = key
+		translated[i] = c.dictionary.Encode(v)
 	}
 
 	// Push to underlying storage
 	c.contents.Push(translated)
 }
 
+// Push values already encoded by this column's dictionary
+func (c *RLEFiniteString32Column) PushCodes(codes []uint32) {
+	c.contents.Push(codes)
+}
+
 // Access the value stored at the named index
 //
 // Provides some guarantees as Access method for the
@@ -55,39 +60,93 @@ func (c *RLEFiniteString32Column) Access(index int) string {
 	raw := c.contents.Access(index)
 
 	// Return the readable string
-	return c.inverter[raw]
+	return c.dictionary.Decode(raw)
+}
+
+// Access the code stored at the named index
+func (c *RLEFiniteString32Column) AccessCode(index int) uint32 {
+	return c.contents.Access(index)
+}
+
+// Overwrite the value stored at the named index
+//
+// Values new to the dictionary are assigned a code, growing
+// it for every column sharing it. As with Access, this
+// performs no range checking.
+func (c *RLEFiniteString32Column) Set(index int, value string) {
+	c.contents.Set(index, c.dictionary.Encode(value))
+}
+
+// Determine the dictionary this column encodes with
+func (c *RLEFiniteString32Column) Dictionary() *Dictionary {
+	return c.dictionary
+}
+
+// Drop this column's reference to its dictionary
+func (c *RLEFiniteString32Column) Release() {
+	c.dictionary.Release()
+}
+
+// Determine the length of this column
+func (c *RLEFiniteString32Column) Length() int {
+	return c.contents.Length()
+}
+
+// Determine the run containing the named index
+//
+// This performs no range checking so an invalid
+// index will cause a panic.
+func (c *RLEFiniteString32Column) RunAt(index int) (start, end int) {
+	return c.contents.RunAt(index)
 }
 
 // Determine all values equal a provided value
-// and return them positionally as a BoolColumn
-func (c *RLEFiniteString32Column) Equal(value string) BoolColumn {
+// and return them as runs
+func (c *RLEFiniteString32Column) EqualRuns(value string) RunSelection {
+	translated, found := c.dictionary.Lookup(value)
+	if !found {
+		return RunSelection{Length: c.Length()}
+	}
 
-	// Translate the string into something
-	// our underlying storage can handle
-	translated := c.translator[value]
+	return c.contents.EqualRuns(translated)
+}
 
-	return c.contents.Equal(translated)
+// Determine all values equal to a member of the provided
+// values and return them as runs
+func (c *RLEFiniteString32Column) WithinRuns(values []string) RunSelection {
+	codes := make([]uint32, 0, len(values))
+	for _, v := range values {
+		if translated, found := c.dictionary.Lookup(v); found {
+			codes = append(codes, translated)
+		}
+	}
+
+	return c.contents.WithinRuns(codes)
+}
+
+// Determine all values equal a provided value
+// and return them positionally as a BoolColumn
+func (c *RLEFiniteString32Column) Equal(value string) BoolColumn {
+	runs := c.EqualRuns(value)
+	return runs.Bools()
 }
 
 // Determine all values equal to a member of the provided values
 // and return them positionally as a BoolColumn
 //
-// Cannot handle empty slices, for single values call Equal instead
+// Each run is tested once against every value, so the
+// result is built without ORing a column per value.
 func (c *RLEFiniteString32Column) Within(values []string) BoolColumn {
+	runs := c.WithinRuns(values)
+	return runs.Bools()
+}
 
-	var query *BoolColumn
-	for _, v := range values {
-		// Translate the string into something
-		// our underlying storage can handle
-		translated := c.translator[v]
-		result := c.contents.Equal(translated)
-		if query == nil {
-			query = &result
-		} else {
-			result = query.OR(result)
-			query = &result
-		}
+// Count the rows holding each distinct value
+func (c *RLEFiniteString32Column) CountBy() map[string]int {
+	counts := make(map[string]int)
+	for code, count := range c.contents.CountBy() {
+		counts[c.dictionary.Decode(code)] += count
 	}
 
-	return *query
+	return counts
 }
@@ -21,11 +21,20 @@ type RLEUInt32Column struct {
 	length   int
 }
 
+// Create a column sized for capacity values
+//
+// Capacity is only a hint, pushing past it grows the vector.
 func NewRLEUInt32Column(capacity int) RLEUInt32Column {
+	// Vectors cannot be empty, so always reserve a slot
+	if capacity < 1 {
+		capacity = 1
+	}
+
 	rle, err := step.New(0, capacity, RLEUint32(0))
 	if err != nil {
 		panic(fmt.Sprintf("failed to create rle vector '%v'", err))
 	}
+	rle.Relaxed = true
 
 	return RLEUInt32Column{
 		contents: rle,
@@ -54,9 +63,24 @@ func (c *RLEUInt32Column) Access(index int) uint32 {
 	return uint32(rleVal.(RLEUint32))
 }
 
+// Overwrite the value stored at the named index
+//
+// The vector splits the run containing index, merging with
+// neighbouring runs when they hold the same value. Only
+// indices already pushed may be set.
+func (c *RLEUInt32Column) Set(index int, value uint32) {
+	if index < 0 || index >= c.length {
+		panic(fmt.Sprintf("index %v out of range [0, %v)", index, c.length))
+	}
+	c.contents.Set(index, RLEUint32(value))
+}
+
 // Determine the length of this column
+//
+// This is the number of values pushed rather than
+// the capacity of the underlying vector
 func (c *RLEUInt32Column) Length() int {
-	return c.contents.Len()
+	return c.length
 }
 
 // Sum all values in the column
@@ -74,23 +98,190 @@ func (c *RLEUInt32Column) Sum() uint64 {
 	return result
 }
 
+// Visit every run of pushed values
+//
+// The remaining capacity is a single run of zeroes we never
+// stored, so it is never visited.
+func (c *RLEUInt32Column) eachRun(fn func(start, end int, v uint32)) {
+	if c.length == 0 {
+		return
+	}
+	c.contents.DoRange(0, c.length, func(start, end int, rleVal step.Equaler) {
+		fn(start, end, uint32(rleVal.(RLEUint32)))
+	})
+}
+
+// Select every run whose value passes test
+func (c *RLEUInt32Column) selectRuns(test func(v uint32) bool) RunSelection {
+	selection := RunSelection{Length: c.length}
+	c.eachRun(func(start, end int, v uint32) {
+		if test(v) {
+			selection.add(start, end)
+		}
+	})
+
+	return selection
+}
+
+// Determine all values equal a provided value
+// and return them as runs
+func (c *RLEUInt32Column) EqualRuns(value uint32) RunSelection {
+	return c.selectRuns(func(v uint32) bool { return v == value })
+}
+
+// Determine all values less than a provided value
+// and return them as runs
+func (c *RLEUInt32Column) LessRuns(value uint32) RunSelection {
+	return c.selectRuns(func(v uint32) bool { return v < value })
+}
+
+// Determine all values greater than or equal to a provided
+// value and return them as runs
+func (c *RLEUInt32Column) MoreRuns(value uint32) RunSelection {
+	return c.selectRuns(func(v uint32) bool { return v >= value })
+}
+
+// Determine all values within [low, high], as SQL's BETWEEN,
+// and return them as runs
+func (c *RLEUInt32Column) BetweenRuns(low, high uint32) RunSelection {
+	return c.selectRuns(func(v uint32) bool { return v >= low && v <= high })
+}
+
+// Determine all values equal to a member of the provided
+// values and return them as runs
+func (c *RLEUInt32Column) WithinRuns(values []uint32) RunSelection {
+	members := make(map[uint32]bool, len(values))
+	for _, v := range values {
+		members[v] = true
+	}
+
+	return c.selectRuns(func(v uint32) bool { return members[v] })
+}
+
 // Determine all values equal a provided value
 // and return them positionally as a BoolColumn
 func (c *RLEUInt32Column) Equal(value uint32) BoolColumn {
-	results := NewBoolColumn()
-	VecStepAfter := func(start, end int, rleVal step.Equaler) {
+	runs := c.EqualRuns(value)
+	return runs.Bools()
+}
+
+// Determine all values less than a provided value
+// and return them positionally as a BoolColumn
+func (c *RLEUInt32Column) Less(value uint32) BoolColumn {
+	runs := c.LessRuns(value)
+	return runs.Bools()
+}
 
-		length := end - start
+// Determine all values greater than or equal to a provided
+// value and return them positionally as a BoolColumn
+func (c *RLEUInt32Column) More(value uint32) BoolColumn {
+	runs := c.MoreRuns(value)
+	return runs.Bools()
+}
 
-		v := uint32(rleVal.(RLEUint32))
-		if v == value {
-			results.PushTrue(length)
-		} else {
-			results.PushFalse(length)
+// Determine all values within [low, high] and return
+// them positionally as a BoolColumn
+func (c *RLEUInt32Column) Between(low, high uint32) BoolColumn {
+	runs := c.BetweenRuns(low, high)
+	return runs.Bools()
+}
+
+// Determine all values equal to a member of the provided values
+// and return them positionally as a BoolColumn
+func (c *RLEUInt32Column) Within(values []uint32) BoolColumn {
+	runs := c.WithinRuns(values)
+	return runs.Bools()
+}
+
+// Determine the smallest value, zero for an empty column
+func (c *RLEUInt32Column) Min() uint32 {
+	var result uint32
+	first := true
+	c.eachRun(func(start, end int, v uint32) {
+		if first || v < result {
+			result = v
+			first = false
 		}
+	})
+
+	return result
+}
+
+// Determine the largest value, zero for an empty column
+func (c *RLEUInt32Column) Max() uint32 {
+	var result uint32
+	c.eachRun(func(start, end int, v uint32) {
+		if v > result {
+			result = v
+		}
+	})
+
+	return result
+}
+
+// Determine how many values equal a provided value
+func (c *RLEUInt32Column) Count(value uint32) int {
+	runs := c.EqualRuns(value)
+	return runs.Count()
+}
 
+// Count the rows holding each distinct value
+//
+// Equivalent to SELECT value, COUNT(*) ... GROUP BY value.
+func (c *RLEUInt32Column) CountBy() map[uint32]int {
+	counts := make(map[uint32]int)
+	c.eachRun(func(start, end int, v uint32) {
+		counts[v] += end - start
+	})
+
+	return counts
+}
+
+// Sum another column of the same length grouped by
+// each distinct value of this column
+//
+// Equivalent to SELECT value, SUM(other) ... GROUP BY value.
+// An RLE column being summed is summed a run at a time where
+// its runs fall within ours.
+func (c *RLEUInt32Column) SumBy(other IntegerColumn) map[uint32]uint64 {
+	sums := make(map[uint32]uint64)
+
+	rle, runs := other.(*RLEUInt32Column)
+	c.eachRun(func(start, end int, v uint32) {
+		var sum uint64
+		for i := start; i < end; {
+			if !runs {
+				sum += uint64(other.Access(i))
+				i++
+				continue
+			}
+
+			_, runEnd := rle.RunAt(i)
+			if runEnd > end {
+				runEnd = end
+			}
+			sum += uint64(rle.Access(i)) * uint64(runEnd-i)
+			i = runEnd
+		}
+		sums[v] += sum
+	})
+
+	return sums
+}
+
+// Determine the run containing the named index
+//
+// This performs no range checking so an invalid
+// index will cause a panic.
+func (c *RLEUInt32Column) RunAt(index int) (start, end int) {
+	start, end, _, err := c.contents.StepAt(index)
+	if err != nil {
+		panic(fmt.Sprintf("failed to read step from vector '%v'", err))
 	}
-	c.contents.Do(VecStepAfter)
 
-	return results
+	// The final run may extend into unused capacity
+	if end > c.length {
+		end = c.length
+	}
+	return start, end
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -36,3 +37,138 @@ func TestRLEUInt32Equal(t *testing.T) {
 	}
 
 }
+
+// Setting within a run splits it, setting to a neighbour's
+// value rejoins it
+func TestRLEUInt32Set(t *testing.T) {
+	col := NewRLEUInt32Column(len(RLEUInt32TestSlice))
+	col.Push(RLEUInt32TestSlice)
+
+	col.Set(2, 7)
+	if start, end := col.RunAt(2); start != 2 || end != 3 {
+		t.Fatalf("set did not split run, [%v, %v)", start, end)
+	}
+	if start, end := col.RunAt(0); start != 0 || end != 2 {
+		t.Fatalf("set damaged leading run, [%v, %v)", start, end)
+	}
+	if col.Sum() != 88 {
+		t.Fatalf("sum is not as expected 88 != %v", col.Sum())
+	}
+
+	col.Set(2, 1)
+	if start, end := col.RunAt(2); start != 0 || end != 6 {
+		t.Fatalf("set did not rejoin run, [%v, %v)", start, end)
+	}
+	if col.Length() != len(RLEUInt32TestSlice) {
+		t.Fatalf("set changed length to %v", col.Length())
+	}
+}
+
+func TestRLEUInt32Predicates(t *testing.T) {
+	col := NewRLEUInt32Column(len(RLEUInt32TestSlice))
+	col.Push(RLEUInt32TestSlice)
+
+	ref := NewUInt32Column()
+	ref.Push(RLEUInt32TestSlice)
+
+	checks := []struct {
+		name     string
+		rle, raw BoolColumn
+	}{
+		{"less", col.Less(3), ref.Less(3)},
+		{"more", col.More(3), ref.More(3)},
+		{"equal", col.Equal(2), ref.Equal(2)},
+	}
+	for _, check := range checks {
+		computed := check.rle.TruthyIndices()
+		reference := check.raw.TruthyIndices()
+		if !reflect.DeepEqual(computed, reference) {
+			t.Fatalf("%v has unexpected result '%v' != '%v'",
+				check.name, computed, reference)
+		}
+	}
+
+	between := col.Between(2, 3)
+	if computed := between.TruthyIndices(); !reflect.DeepEqual(computed,
+		[]int{6, 7, 8, 9, 10, 12}) {
+		t.Fatalf("between has unexpected result '%v'", computed)
+	}
+
+	within := col.Within([]uint32{3, 60})
+	if computed := within.TruthyIndices(); !reflect.DeepEqual(computed,
+		[]int{9, 10, 11}) {
+		t.Fatalf("within has unexpected result '%v'", computed)
+	}
+
+	// Adjacent selected runs are merged
+	runs := col.LessRuns(3)
+	if !reflect.DeepEqual(runs.Runs, []RowRange{{0, 9}, {12, 15}}) {
+		t.Fatalf("less runs are unexpected '%v'", runs.Runs)
+	}
+	if runs.Count() != 12 {
+		t.Fatalf("less runs count %v != 12", runs.Count())
+	}
+}
+
+func TestRLEUInt32Aggregates(t *testing.T) {
+	col := NewRLEUInt32Column(len(RLEUInt32TestSlice))
+	if col.Min() != 0 || col.Max() != 0 {
+		t.Fatalf("empty column has min %v max %v", col.Min(), col.Max())
+	}
+	col.Push(RLEUInt32TestSlice)
+
+	if col.Min() != 1 || col.Max() != 60 {
+		t.Fatalf("min %v max %v != 1, 60", col.Min(), col.Max())
+	}
+	if col.Count(1) != 8 {
+		t.Fatalf("count of 1 is %v != 8", col.Count(1))
+	}
+
+	counts := col.CountBy()
+	if !reflect.DeepEqual(counts, map[uint32]int{1: 8, 2: 4, 3: 2, 60: 1}) {
+		t.Fatalf("counts are unexpected '%v'", counts)
+	}
+
+	// Summing a column against itself is each value times its count,
+	// exercised over both encodings of the summed column
+	ref := NewUInt32Column()
+	ref.Push(RLEUInt32TestSlice)
+	expected := map[uint32]uint64{1: 8, 2: 8, 3: 6, 60: 60}
+	for _, other := range []IntegerColumn{&col, &ref} {
+		if sums := col.SumBy(other); !reflect.DeepEqual(sums, expected) {
+			t.Fatalf("sums are unexpected '%v'", sums)
+		}
+	}
+}
+
+// Combining runs with a BoolColumn only touches the gaps
+func TestRunSelectionCombine(t *testing.T) {
+	col := NewRLEUInt32Column(len(RLEUInt32TestSlice))
+	col.Push(RLEUInt32TestSlice)
+
+	// Every odd row, spanning several words
+	odd := NewBoolColumn()
+	for i := 0; i < 200; i++ {
+		odd.Push([]bool{i%2 == 1})
+	}
+
+	runs := col.MoreRuns(2)
+	and := runs.AND(odd)
+	if computed := and.TruthyIndices(); !reflect.DeepEqual(computed,
+		[]int{7, 9, 11}) {
+		t.Fatalf("and has unexpected result '%v'", computed)
+	}
+
+	none := NewBoolColumn()
+	none.PushFalse(len(RLEUInt32TestSlice))
+	or := col.EqualRuns(3).OR(none)
+	if computed := or.TruthyIndices(); !reflect.DeepEqual(computed,
+		[]int{9, 10}) {
+		t.Fatalf("or has unexpected result '%v'", computed)
+	}
+
+	both := col.LessRuns(3).Intersect(col.MoreRuns(2))
+	if !reflect.DeepEqual(both.Runs, []RowRange{{6, 9}, {12, 13}}) {
+		t.Fatalf("intersection is unexpected '%v'", both.Runs)
+	}
+}
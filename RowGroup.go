@@ -0,0 +1,61 @@
+package main
+
+// Rows held by each row group
+//
+// Columns store their values in one chunk per row group, every
+// chunk but the last holding exactly this many. It is a whole
+// number of BoolColumn words, so row groups can be scanned
+// independently.
+const RowGroupSize = 1 << rowGroupShift
+
+const rowGroupShift = 16
+const rowGroupMask = RowGroupSize - 1
+
+// A half-open range of rows [Start, End)
+type RowRange struct {
+	Start, End int
+}
+
+// Determine how many row groups hold length rows
+func rowGroups(length int) int {
+	return (length + RowGroupSize - 1) >> rowGroupShift
+}
+
+// Determine the rows of a group in a length row table
+func groupRange(group, length int) RowRange {
+	r := RowRange{group << rowGroupShift, (group + 1) << rowGroupShift}
+	if r.End > length {
+		r.End = length
+	}
+	return r
+}
+
+// Statistics describing a single row group of a PriceDB
+type RowGroupStats struct {
+	Rows RowRange
+
+	// Rows deleted but not yet compacted away
+	Deleted int
+
+	// Bounds of the group's prices and times
+	Prices UInt32Zone
+	Times  TimeZone
+}
+
+// Describe every row group of the database
+func (db *PriceDB) RowGroups() []RowGroupStats {
+	length := db.Prices.Length()
+
+	stats := make([]RowGroupStats, rowGroups(length))
+	for g := range stats {
+		r := groupRange(g, length)
+		stats[g] = RowGroupStats{
+			Rows:    r,
+			Deleted: db.deleted.countRange(r),
+			Prices:  db.Prices.zones[g],
+			Times:   db.Times.zones[g],
+		}
+	}
+
+	return stats
+}
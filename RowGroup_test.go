@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Columns split across row groups behave exactly as one
+// contiguous column, including past a million rows
+func TestRowGroupsPastMillion(t *testing.T) {
+	const length = 1<<20 + 100
+
+	col := NewUInt32Column()
+	values := make([]uint32, 1000)
+	for pushed := 0; pushed < length; pushed += len(values) {
+		batch := values
+		if length-pushed < len(batch) {
+			batch = batch[:length-pushed]
+		}
+		for i := range batch {
+			batch[i] = uint32((pushed + i) % 7)
+		}
+		col.Push(batch)
+	}
+
+	if col.Length() != length || len(col.chunks) != rowGroups(length) {
+		t.Fatalf("bad layout, %v rows in %v chunks", col.Length(), len(col.chunks))
+	}
+	for g, chunk := range col.chunks[:len(col.chunks)-1] {
+		if len(chunk) != RowGroupSize {
+			t.Fatalf("chunk %v holds %v rows", g, len(chunk))
+		}
+	}
+	for _, i := range []int{0, RowGroupSize - 1, RowGroupSize, length - 1} {
+		if col.Access(i) != uint32(i%7) {
+			t.Fatalf("bad value at %v", i)
+		}
+	}
+
+	zeros := col.Equal(0)
+	indices := zeros.TruthyIndices()
+	if len(indices) != (length+6)/7 || indices[len(indices)-1] != length-1-(length-1)%7 {
+		t.Fatalf("bad selection past a million rows, %v", len(indices))
+	}
+
+	// Selections built by pushing grow too
+	pushed := NewBoolColumn()
+	pushed.PushFalse(length - 1)
+	pushed.PushTrue(1)
+	if last := pushed.TruthyIndices(); len(last) != 1 || last[0] != length-1 {
+		t.Fatalf("bad pushed selection, %v", last)
+	}
+}
+
+func TestRLEGrowsPastCapacity(t *testing.T) {
+	col := NewRLEUInt32Column(4)
+	col.Push([]uint32{1, 1, 2, 2, 2, 3, 3, 3})
+
+	if col.Length() != 8 || col.Access(7) != 3 || col.Sum() != 17 {
+		t.Fatalf("bad grown column, %v %v", col.Length(), col.Sum())
+	}
+}
+
+func TestPriceDBRowGroups(t *testing.T) {
+	db := NewPriceDB()
+	batch := make([]PriceTuple, RowGroupSize+10)
+	for i := range batch {
+		batch[i] = PriceTuple{Name: "Griselbrand", Set: "Avacyn Restored",
+			Price: uint32(i), Time: time.Unix(int64(i), 0)}
+	}
+	db.Push(batch)
+
+	deleted := NewBoolColumn()
+	deleted.PushFalse(RowGroupSize + 2)
+	deleted.PushTrue(3)
+	db.Delete(deleted)
+
+	groups := db.RowGroups()
+	if len(groups) != 2 {
+		t.Fatalf("bad group count, %v", len(groups))
+	}
+	if groups[0].Rows != (RowRange{0, RowGroupSize}) || groups[0].Deleted != 0 {
+		t.Fatalf("bad first group, %v", groups[0])
+	}
+	if groups[1].Rows != (RowRange{RowGroupSize, RowGroupSize + 10}) || groups[1].Deleted != 3 {
+		t.Fatalf("bad second group, %v", groups[1])
+	}
+	if groups[1].Prices != (UInt32Zone{RowGroupSize, RowGroupSize + 9}) {
+		t.Fatalf("bad second group prices, %v", groups[1].Prices)
+	}
+
+	// Materialization reaches across groups
+	late := db.Times.After(time.Unix(RowGroupSize-3, 0))
+	tuples := db.MaterializeFromBools(late)
+	if len(tuples) != 12-3 || tuples[0].Price != RowGroupSize-2 {
+		t.Fatalf("bad materialized rows, %v", len(tuples))
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+// A selection held as runs of rows rather than one bit per row
+//
+// Predicates on run length encoded columns produce these directly
+// from their runs, so a sorted column selects millions of rows
+// with a handful of ranges.
+type RunSelection struct {
+	// Selected rows, ascending and never adjacent
+	Runs []RowRange
+
+	// Rows in the column the selection was made against
+	Length int
+}
+
+// Add a run of selected rows, merging it with the previous
+// run when they touch
+//
+// Runs must be added in ascending order.
+func (s *RunSelection) add(start, end int) {
+	if start >= end {
+		return
+	}
+	if last := len(s.Runs) - 1; last >= 0 && s.Runs[last].End == start {
+		s.Runs[last].End = end
+		return
+	}
+	s.Runs = append(s.Runs, RowRange{start, end})
+}
+
+// Determine how many rows are selected
+func (s RunSelection) Count() int {
+	count := 0
+	for _, r := range s.Runs {
+		count += r.End - r.Start
+	}
+	return count
+}
+
+// Expand the selection into a BoolColumn, filling whole
+// words for each run
+func (s RunSelection) Bools() BoolColumn {
+	results := NewBoolColumn()
+
+	previous := 0
+	for _, r := range s.Runs {
+		results.PushFalse(r.Start - previous)
+		results.PushTrue(r.End - r.Start)
+		previous = r.End
+	}
+	results.PushFalse(s.Length - previous)
+
+	return results
+}
+
+// Narrow a BoolColumn to the selected rows in place and return it
+//
+// Only the gaps between runs are touched, each cleared a
+// word at a time, so the runs are never expanded.
+func (s RunSelection) AND(b BoolColumn) BoolColumn {
+	limit := uint(len(b.contents.Bytes()) * 64)
+	clear := func(start, end uint) {
+		if end > limit {
+			end = limit
+		}
+		b.fill(start, end, false)
+	}
+
+	previous := uint(0)
+	for _, r := range s.Runs {
+		clear(previous, uint(r.Start))
+		previous = uint(r.End)
+	}
+	clear(previous, limit)
+
+	return b
+}
+
+// Widen a BoolColumn to include the selected rows in place
+// and return it
+func (s RunSelection) OR(b BoolColumn) BoolColumn {
+	for _, r := range s.Runs {
+		b.SetRange(r.Start, r.End)
+	}
+	if uint(s.Length) > b.end {
+		b.end = uint(s.Length)
+	}
+
+	return b
+}
+
+// Intersect two run selections without expanding either
+func (s RunSelection) Intersect(other RunSelection) RunSelection {
+	result := RunSelection{Length: s.Length}
+
+	i, j := 0, 0
+	for i < len(s.Runs) && j < len(other.Runs) {
+		a, b := s.Runs[i], other.Runs[j]
+
+		start, end := a.Start, a.End
+		if b.Start > start {
+			start = b.Start
+		}
+		if b.End < end {
+			end = b.End
+		}
+		result.add(start, end)
+
+		if a.End < b.End {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return result
+}
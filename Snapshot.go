@@ -0,0 +1,190 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// A consistent, read-only view of a PriceDB
+//
+// Every column is bounded to the rows present when the snapshot
+// was taken, so predicates produce BoolColumns of exactly
+// Watermark rows and materialization never sees a partial Push.
+// Later updates and deletes copy what they modify rather than
+// writing through to the snapshot. Dictionaries are shared and
+// keep growing, strings added later are unknown to the snapshot.
+//
+// Snapshots need no locking to read and any number may be held.
+// Writing to one panics. Each snapshot retains the dictionaries
+// it decodes with until released.
+type Snapshot struct {
+	PriceDB
+
+	// Number of rows visible
+	Watermark int
+
+	// Dictionary sizes when taken, strings added later are
+	// unknown to the snapshot's columns
+	NameVersion, SetVersion int
+}
+
+// Take a snapshot of the database's current state
+//
+// This costs a handful of slice headers, the first write
+// afterwards which modifies rows in place pays for a copy.
+func (db *PriceDB) Snapshot() Snapshot {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	watermark := db.Prices.Length()
+
+	// Attached projections replace their delta on every
+	// change, so copies of them are stable once given
+	// their own set of removed rows
+	projections := &ProjectionRegistry{}
+	for _, proj := range db.projections.projections {
+		copied := *proj
+		copied.removed = proj.removed.clone()
+		proj.shared = true
+		copied.Names.Dictionary().Retain()
+		copied.Sets.Dictionary().Retain()
+		projections.projections = append(projections.projections, &copied)
+	}
+
+	nameVersion := db.Names.Dictionary().Size()
+	setVersion := db.Sets.Dictionary().Size()
+
+	view := PriceDB{
+		Names:  db.Names.bounded(watermark, nameVersion),
+		Sets:   db.Sets.bounded(watermark, setVersion),
+		Prices: db.Prices.bounded(watermark),
+		Times:  db.Times.bounded(watermark),
+
+		projections: projections,
+		deleted:     db.deleted,
+		snapshot:    true,
+
+		lock: &sync.RWMutex{},
+	}
+
+	db.frozen = true
+
+	return Snapshot{
+		PriceDB:     view,
+		Watermark:   watermark,
+		NameVersion: nameVersion,
+		SetVersion:  setVersion,
+	}
+}
+
+// Drop the snapshot's references to its dictionaries
+//
+// The snapshot must not be read afterwards.
+func (s Snapshot) Release() {
+	s.Names.Release()
+	s.Sets.Release()
+	for _, proj := range s.projections.projections {
+		proj.Names.Dictionary().Release()
+		proj.Sets.Dictionary().Release()
+	}
+}
+
+// Take the write lock, refusing writes to snapshots
+func (db *PriceDB) lockWriter() {
+	if db.snapshot {
+		panic("write to a snapshot of a PriceDB")
+	}
+	db.lock.Lock()
+}
+
+// Give the database private copies of everything a snapshot
+// may share, ahead of modifying rows in place
+//
+// Appending needs no copy as snapshots never look
+// past their watermark.
+func (db *PriceDB) thaw() {
+	if !db.frozen {
+		return
+	}
+
+	db.Names.copyChunks()
+	db.Sets.copyChunks()
+	db.Prices.copyChunks()
+	db.Times.copyChunks()
+	db.attachDeleteVector(db.deleted.clone())
+
+	db.frozen = false
+}
+
+// Views of columns which can never see past length rows
+//
+// The final chunk's capacity is capped so appends to either the
+// view or the original cannot write into memory the other reads.
+func (c *UInt32Column) bounded(length int) UInt32Column {
+	chunks := make([][]uint32, rowGroups(length))
+	copy(chunks, c.chunks)
+	if last := len(chunks) - 1; last >= 0 {
+		n := length - last<<rowGroupShift
+		chunks[last] = chunks[last][:n:n]
+	}
+
+	return UInt32Column{
+		chunks:  chunks,
+		length:  length,
+		zones:   append([]UInt32Zone(nil), c.zones[:len(chunks)]...),
+		deleted: c.deleted,
+	}
+}
+func (c *FiniteString32Column) bounded(length, version int) FiniteString32Column {
+	// Only the final group's codes can still change
+	// without a thaw
+	present := append([]*presentCodes(nil), c.present[:rowGroups(length)]...)
+	if last := len(present) - 1; last >= 0 {
+		present[last] = present[last].clone()
+	}
+
+	// Bitmap indexes are updated in place so are left
+	// behind, snapshots scan instead
+	return FiniteString32Column{
+		contents:   c.contents.bounded(length),
+		present:    present,
+		dictionary: c.dictionary.Retain(),
+
+		// Codes run from 1, so a dictionary of version
+		// strings holds codes below version + 1
+		version: uint32(version) + 1,
+	}
+}
+func (c *TimeColumn) bounded(length int) TimeColumn {
+	chunks := make([][]time.Time, rowGroups(length))
+	copy(chunks, c.chunks)
+	if last := len(chunks) - 1; last >= 0 {
+		n := length - last<<rowGroupShift
+		chunks[last] = chunks[last][:n:n]
+	}
+
+	return TimeColumn{
+		chunks:  chunks,
+		length:  length,
+		zones:   append([]TimeZone(nil), c.zones[:len(chunks)]...),
+		deleted: c.deleted,
+	}
+}
+
+// Replace every chunk with a private copy
+func (c *FiniteString32Column) copyChunks() {
+	c.contents.copyChunks()
+	for i, present := range c.present {
+		c.present[i] = present.clone()
+	}
+}
+func (c *UInt32Column) copyChunks() {
+	for i, chunk := range c.chunks {
+		c.chunks[i] = append([]uint32(nil), chunk...)
+	}
+}
+func (c *TimeColumn) copyChunks() {
+	for i, chunk := range c.chunks {
+		c.chunks[i] = append([]time.Time(nil), chunk...)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshotIsolation(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	db.Attach(NameTimeSpec)
+	snap := db.Snapshot()
+
+	// Appends are invisible
+	db.Push([]PriceTuple{{Name: "Aether Vial", Set: "Darksteel",
+		Price: 500, Time: time.Unix(0, 0)}})
+	vial := snap.Names.Equal("Aether Vial")
+	if len(vial.TruthyIndices()) != 0 || vial.end != 12 {
+		t.Fatalf("snapshot saw append")
+	}
+	if snap.Watermark != 12 || snap.Prices.Length() != 12 {
+		t.Fatalf("bad watermark, %v", snap.Watermark)
+	}
+	if snap.NameVersion != 3 {
+		t.Fatalf("bad name version, %v != 3", snap.NameVersion)
+	}
+
+	// Strings added to the shared dictionary are unknown
+	if _, found := db.Names.Dictionary().Lookup("Aether Vial"); !found {
+		t.Fatalf("append did not reach the dictionary")
+	}
+	if _, found := snap.Names.lookup("Aether Vial"); found {
+		t.Fatalf("snapshot resolved a name added after it")
+	}
+	if _, found := snap.Names.lookup("Griselbrand"); !found {
+		t.Fatalf("snapshot lost a name added before it")
+	}
+	within := snap.Names.Within([]string{"Aether Vial", "Windswept Heath"})
+	heath := snap.Names.Equal("Windswept Heath")
+	if len(within.TruthyIndices()) != len(heath.TruthyIndices()) {
+		t.Fatalf("snapshot within matched a name added after it")
+	}
+
+	// Updates and deletes are invisible
+	heath = db.Names.Equal("Windswept Heath")
+	if _, err := db.Update(heath, FieldPrice, uint32(1)); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	db.Delete(db.Names.Equal("Griselbrand"))
+
+	original := setupSyntheticPriceDB()
+	if snap.Prices.Sum() != original.Prices.Sum() {
+		t.Fatalf("snapshot saw update or delete")
+	}
+	results, err := snap.Execute(Query{Where: []Predicate{
+		{Field: FieldName, Op: OpEqual, Value: "Griselbrand"},
+	}})
+	if err != nil || len(results) != 5 {
+		t.Fatalf("snapshot saw delete, %v %v", len(results), err)
+	}
+	for _, tuple := range snap.MaterializeFromBools(snap.Names.Equal("Windswept Heath")) {
+		if tuple.Price == 1 {
+			t.Fatalf("snapshot saw update")
+		}
+	}
+
+	// The database itself sees everything
+	if db.LiveLength() != 8 || db.Prices.Sum() != 3000+3100+2900+500+4 {
+		t.Fatalf("database missed writes, %v", db.Prices.Sum())
+	}
+}
+
+func TestSnapshotRefusesWrites(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	snap := db.Snapshot()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("snapshot accepted a write")
+		}
+	}()
+	snap.Push(syntheticPriceTuples())
+}
+
+// Snapshots taken while ingesting always hold whole batches
+// with every column the same length
+//
+// Run with -race to check synchronization.
+func TestSnapshotDuringIngest(t *testing.T) {
+	db := NewPriceDB()
+
+	const batches, batchSize = 50, 20
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for b := 0; b < batches; b++ {
+			batch := make([]PriceTuple, batchSize)
+			for i := range batch {
+				batch[i] = PriceTuple{Name: fmt.Sprintf("Card %v", b),
+					Set: "Onslaught", Price: 1, Time: time.Unix(int64(b), 0)}
+			}
+			db.Push(batch)
+			if b%5 == 0 {
+				db.Update(db.Names.Equal("Card 0"), FieldPrice, uint32(b))
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		snap := db.Snapshot()
+		if snap.Watermark%batchSize != 0 {
+			t.Fatalf("snapshot holds a partial batch, %v", snap.Watermark)
+		}
+		if snap.Names.Length() != snap.Watermark ||
+			snap.Sets.Length() != snap.Watermark {
+			t.Fatalf("snapshot columns differ in length")
+		}
+		all := snap.Live()
+		if tuples := snap.MaterializeFromBools(all); len(tuples) != snap.Watermark {
+			t.Fatalf("bad materialized length, %v", len(tuples))
+		}
+	}
+	wg.Wait()
+}
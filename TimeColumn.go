@@ -5,17 +5,44 @@ import (
 )
 
 type TimeColumn struct {
-	contents []time.Time
+	// Values split by row group, see RowGroupSize
+	chunks [][]time.Time
+	length int
+
+	// Bounds of each chunk's times
+	zones []TimeZone
+
+	// Rows skipped by predicates, possibly nil
+	deleted *DeleteVector
 }
 
 func NewTimeColumn() TimeColumn {
 	return TimeColumn{
-		contents: make([]time.Time, 0),
+		chunks: make([][]time.Time, 0),
 	}
 }
 
 func (c *TimeColumn) Push(values []time.Time) {
-	c.contents = append(c.contents, values...)
+	c.length += len(values)
+
+	for len(values) > 0 {
+		last := len(c.chunks) - 1
+		if last < 0 || len(c.chunks[last]) == RowGroupSize {
+			c.chunks = append(c.chunks, make([]time.Time, 0))
+			c.zones = append(c.zones, TimeZone{values[0], values[0]})
+			last++
+		}
+
+		room := RowGroupSize - len(c.chunks[last])
+		if room > len(values) {
+			room = len(values)
+		}
+		for _, v := range values[:room] {
+			c.zones[last].widen(v)
+		}
+		c.chunks[last] = append(c.chunks[last], values[:room]...)
+		values = values[room:]
+	}
 }
 
 // Access the value stored at the named index
@@ -24,16 +51,39 @@ func (c *TimeColumn) Push(values []time.Time) {
 // index will cause a panic. The caller is responsible
 // for ensuring index is within bounds
 func (c *TimeColumn) Access(index int) time.Time {
-	return c.contents[index]
+	return c.chunks[index>>rowGroupShift][index&rowGroupMask]
+}
+
+// Overwrite the value stored at the named index
+//
+// As with Access, this performs no range checking.
+func (c *TimeColumn) Set(index int, value time.Time) {
+	c.chunks[index>>rowGroupShift][index&rowGroupMask] = value
+	c.zones[index>>rowGroupShift].widen(value)
+}
+
+// Determine the length of this column
+func (c *TimeColumn) Length() int {
+	return c.length
+}
+
+// Access the values of a single row group
+func (c *TimeColumn) Chunk(group int) []time.Time {
+	return c.chunks[group]
 }
 
 // Determine all times happening after a certain point
 // and return them positionally as a BoolColumn
 func (c *TimeColumn) After(when time.Time) BoolColumn {
-	results := NewBoolColumn()
-	for _, v := range c.contents {
-		results.Push([]bool{v.After(when)})
-	}
+	results := scanGroups(c.length, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		packZone(c.zones[group].after(when), words, len(chunk), func() {
+			packWords(words, len(chunk), func(i int) bool {
+				return chunk[i].After(when)
+			})
+		})
+	})
+	c.deleted.mask(results)
 
 	return results
 }
@@ -44,9 +94,42 @@ func (c *TimeColumn) After(when time.Time) BoolColumn {
 // This lets us operate inplace on an existing BoolColumn, saving
 // allocations
 func (c *TimeColumn) ANDAfter(when time.Time, results BoolColumn) {
-	for i, v := range c.contents {
-		if !v.After(when) {
-			results.Clear(i)
-		}
-	}
+	andGroups(c.length, results, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		andZone(c.zones[group].after(when), words, len(chunk), func(i int) bool {
+			return chunk[i].After(when)
+		})
+	})
+	c.deleted.mask(results)
+}
+
+// Determine all times happening before a certain point
+// and return them positionally as a BoolColumn
+func (c *TimeColumn) Before(when time.Time) BoolColumn {
+	results := scanGroups(c.length, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		packZone(c.zones[group].before(when), words, len(chunk), func() {
+			packWords(words, len(chunk), func(i int) bool {
+				return chunk[i].Before(when)
+			})
+		})
+	})
+	c.deleted.mask(results)
+
+	return results
+}
+
+// Determine all times happening before a certain point
+// and clear those not before that time
+//
+// This lets us operate inplace on an existing BoolColumn, saving
+// allocations
+func (c *TimeColumn) ANDBefore(when time.Time, results BoolColumn) {
+	andGroups(c.length, results, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		andZone(c.zones[group].before(when), words, len(chunk), func(i int) bool {
+			return chunk[i].Before(when)
+		})
+	})
+	c.deleted.mask(results)
 }
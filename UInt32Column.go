@@ -1,17 +1,44 @@
 package main
 
 type UInt32Column struct {
-	contents []uint32
+	// Values split by row group, see RowGroupSize
+	chunks [][]uint32
+	length int
+
+	// Bounds of each chunk's values
+	zones []UInt32Zone
+
+	// Rows skipped by predicates and aggregates, possibly nil
+	deleted *DeleteVector
 }
 
 func NewUInt32Column() UInt32Column {
 	return UInt32Column{
-		contents: make([]uint32, 0),
+		chunks: make([][]uint32, 0),
 	}
 }
 
 func (c *UInt32Column) Push(values []uint32) {
-	c.contents = append(c.contents, values...)
+	c.length += len(values)
+
+	for len(values) > 0 {
+		last := len(c.chunks) - 1
+		if last < 0 || len(c.chunks[last]) == RowGroupSize {
+			c.chunks = append(c.chunks, make([]uint32, 0))
+			c.zones = append(c.zones, UInt32Zone{values[0], values[0]})
+			last++
+		}
+
+		room := RowGroupSize - len(c.chunks[last])
+		if room > len(values) {
+			room = len(values)
+		}
+		for _, v := range values[:room] {
+			c.zones[last].widen(v)
+		}
+		c.chunks[last] = append(c.chunks[last], values[:room]...)
+		values = values[room:]
+	}
 }
 
 // Access the value stored at the named index
@@ -20,12 +47,35 @@ func (c *UInt32Column) Push(values []uint32) {
 // index will cause a panic. The caller is responsible
 // for ensuring index is within bounds
 func (c *UInt32Column) Access(index int) uint32 {
-	return c.contents[index]
+	return c.chunks[index>>rowGroupShift][index&rowGroupMask]
+}
+
+// Overwrite the value stored at the named index
+//
+// As with Access, this performs no range checking.
+func (c *UInt32Column) Set(index int, value uint32) {
+	c.chunks[index>>rowGroupShift][index&rowGroupMask] = value
+	c.zones[index>>rowGroupShift].widen(value)
 }
 
 // Determine the length of this column
 func (c *UInt32Column) Length() int {
-	return len(c.contents)
+	return c.length
+}
+
+// Access the values of a single row group
+func (c *UInt32Column) Chunk(group int) []uint32 {
+	return c.chunks[group]
+}
+
+// Gather every value into a single slice
+func (c *UInt32Column) values() []uint32 {
+	values := make([]uint32, 0, c.length)
+	for _, chunk := range c.chunks {
+		values = append(values, chunk...)
+	}
+
+	return values
 }
 
 // Determine the difference between a provided value
@@ -33,18 +83,41 @@ func (c *UInt32Column) Length() int {
 func (c *UInt32Column) Delta(value uint32) UInt32Column {
 	results := NewUInt32Column()
 
-	for _, v := range c.contents {
-		results.Push([]uint32{v - value})
+	for _, chunk := range c.chunks {
+		delta := make([]uint32, len(chunk))
+		for i, v := range chunk {
+			delta[i] = v - value
+		}
+		results.Push(delta)
 	}
 
 	return results
 }
 
 // Sum all values in the column
+//
+// Each row group is summed separately before the
+// partials are added.
 func (c *UInt32Column) Sum() uint64 {
+	partials := make([]uint64, len(c.chunks))
+
+	eachGroup(c.length, func(group int, r RowRange) {
+		var result uint64
+		if c.deleted.countRange(r) > 0 {
+			for i, v := range c.chunks[group] {
+				if !c.deleted.Deleted(r.Start + i) {
+					result = result + uint64(v)
+				}
+			}
+		} else {
+			result = sumUInt32(c.chunks[group])
+		}
+		partials[group] = result
+	})
+
 	var result uint64
-	for _, v := range c.contents {
-		result = result + uint64(v)
+	for _, partial := range partials {
+		result = result + partial
 	}
 
 	return result
@@ -53,28 +126,41 @@ func (c *UInt32Column) Sum() uint64 {
 // Determine all values less than a provided value
 // and return them positionally as a BoolColumn
 func (c *UInt32Column) Less(value uint32) BoolColumn {
-	results := NewBoolColumn()
-	for _, v := range c.contents {
-		results.Push([]bool{v < value})
-	}
+	results := scanGroups(c.length, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		packZone(c.zones[group].less(value), words, len(chunk), func() {
+			lessWordsUInt32(chunk, value, words)
+		})
+	})
+	c.deleted.mask(results)
 
 	return results
 }
 
-// Determine all values less than a provided value
-// and return them positionally as a BoolColumn
+// Determine all values greater than or equal to a provided
+// value and return them positionally as a BoolColumn
 func (c *UInt32Column) More(value uint32) BoolColumn {
-	less := c.Less(value)
-	return less.Not()
+	results := scanGroups(c.length, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		packZone(c.zones[group].more(value), words, len(chunk), func() {
+			moreWordsUInt32(chunk, value, words)
+		})
+	})
+	c.deleted.mask(results)
+
+	return results
 }
 
 // Determine all values equal a provided value
 // and return them positionally as a BoolColumn
 func (c *UInt32Column) Equal(value uint32) BoolColumn {
-	results := NewBoolColumn()
-	for _, v := range c.contents {
-		results.Push([]bool{v == value})
-	}
+	results := scanGroups(c.length, func(group int, words []uint64) {
+		chunk := c.chunks[group]
+		packZone(c.zones[group].equal(value), words, len(chunk), func() {
+			equalWordsUInt32(chunk, value, words)
+		})
+	})
+	c.deleted.mask(results)
 
 	return results
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Overwrite a field of every selected row with a single value
+//
+// Values must be a string for names and sets, a uint32 for
+// prices and a time.Time for times. Deleted rows are left alone.
+// With a key index enabled, an update which would give two rows
+// the same key is refused and nothing changes. Attached
+// projections are updated in place, or through their delta
+// when the field is one of their sort keys.
+//
+// Returns the number of rows updated.
+func (db *PriceDB) Update(selection BoolColumn,
+	field PriceField, value interface{}) (int, error) {
+
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	return db.update(selection, field, value)
+}
+
+func (db *PriceDB) update(selection BoolColumn,
+	field PriceField, value interface{}) (int, error) {
+
+	check := Predicate{Field: field, Op: OpEqual, Value: value}
+	if err := check.validate(); err != nil {
+		return 0, err
+	}
+
+	length := db.Prices.Length()
+	rows := make([]int, 0)
+	for p, ok := selection.NextTruthy(0); ok && p < length; p, ok = selection.NextTruthy(p + 1) {
+		if !db.deleted.Deleted(p) {
+			rows = append(rows, p)
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	rekey := db.keys != nil && field != FieldPrice
+	var keys []rowKey
+	if rekey {
+		var err error
+		keys, err = db.keys.rekey(db, rows, field, value)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	changes := make([]rowChange, len(rows))
+	for i, before := range db.MaterializePositions(rows) {
+		after := before
+		switch field {
+		case FieldName:
+			after.Name = value.(string)
+		case FieldSet:
+			after.Set = value.(string)
+		case FieldPrice:
+			after.Price = value.(uint32)
+		case FieldTime:
+			after.Time = value.(time.Time)
+		}
+		changes[i] = rowChange{before, after}
+	}
+
+	db.thaw()
+	for _, p := range rows {
+		switch field {
+		case FieldName:
+			db.Names.Set(p, value.(string))
+		case FieldSet:
+			db.Sets.Set(p, value.(string))
+		case FieldPrice:
+			db.Prices.Set(p, value.(uint32))
+		case FieldTime:
+			db.Times.Set(p, value.(time.Time))
+		}
+	}
+
+	if rekey {
+		for _, key := range keys {
+			delete(db.keys.rows, key)
+		}
+		for _, p := range rows {
+			db.keys.rows[db.keyAt(p)] = p
+		}
+	}
+
+	db.changeProjections(changes)
+
+	return len(rows), nil
+}
+
+// A stored row before and after being overwritten
+type rowChange struct {
+	before, after PriceTuple
+}
+
+// Apply changes to stored rows to every attached projection
+//
+// Rows are found by binary search on the projection's sort
+// keys and overwritten in place where they stay in order.
+// The rest are appended together through the delta.
+func (db *PriceDB) changeProjections(changes []rowChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	for _, proj := range db.projections.projections {
+		after := make([]PriceTuple, 0)
+		for _, change := range changes {
+			found, moved := proj.replace(change.before, change.after)
+			if moved {
+				after = append(after, change.after)
+			}
+			if found {
+				continue
+			}
+
+			// A row changed again before being appended
+			for i := range after {
+				if sameTuple(after[i], change.before) {
+					after[i] = change.after
+					break
+				}
+			}
+		}
+		proj.Append(after)
+	}
+}
+
+// Overwrite a field of the row holding a key
+//
+// Fails if no row holds the key.
+func (db *PriceDB) UpdateKey(name, set string, when time.Time,
+	field PriceField, value interface{}) error {
+
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	row, found := db.Lookup(name, set, when)
+	if !found {
+		return fmt.Errorf("no row with key (%v, %v, %v)", name, set, when)
+	}
+
+	selection := NewBoolColumn()
+	selection.PushFalse(db.Prices.Length())
+	selection.Set(row)
+
+	_, err := db.update(selection, field, value)
+	return err
+}
+
+// Determine the current keys of rows about to have a key field
+// overwritten, failing if the new keys would collide
+func (index *KeyIndex) rekey(db *PriceDB, rows []int,
+	field PriceField, value interface{}) ([]rowKey, error) {
+
+	// Encoding here may grow the dictionary ahead of a
+	// refused update, which is harmless as codes are only
+	// ever added
+	var code uint32
+	switch field {
+	case FieldName:
+		code = db.Names.Dictionary().Encode(value.(string))
+	case FieldSet:
+		code = db.Sets.Dictionary().Encode(value.(string))
+	}
+
+	updated := make(map[int]bool, len(rows))
+	for _, p := range rows {
+		updated[p] = true
+	}
+
+	old := make([]rowKey, len(rows))
+	fresh := make(map[rowKey]bool, len(rows))
+	for i, p := range rows {
+		old[i] = db.keyAt(p)
+
+		key := old[i]
+		switch field {
+		case FieldName:
+			key.name = code
+		case FieldSet:
+			key.set = code
+		case FieldTime:
+			key.nanos = value.(time.Time).UnixNano()
+		}
+
+		existing, stored := index.rows[key]
+		if fresh[key] || (stored && !updated[existing]) {
+			return nil, fmt.Errorf("update of row %v to %v = %v duplicates a key",
+				p, field, value)
+		}
+		fresh[key] = true
+	}
+
+	return old, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Postgres equivalent
+//  UPDATE prices.mtgprice SET price = 9100
+//  	WHERE name = 'Windswept Heath' AND price > 9400;
+func TestUpdatePrices(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(PriceSpec)
+
+	query := db.Names.Equal("Windswept Heath")
+	query.AND(db.Prices.More(9401))
+	updated, err := db.Update(query, FieldPrice, uint32(9100))
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if updated != 2 {
+		t.Fatalf("bad update count, %v != 2", updated)
+	}
+
+	changed := db.Prices.Equal(9100)
+	if len(changed.TruthyIndices()) != 2 {
+		t.Fatalf("update not visible, %v", changed.TruthyIndices())
+	}
+
+	// Moved rows go through the delta of price ordered
+	// projections, landing in order once merged
+	if proj.LiveLength() != 12 || proj.DeltaLength() != 2 {
+		t.Fatalf("bad projection lengths, %v with %v in delta",
+			proj.LiveLength(), proj.DeltaLength())
+	}
+	cheaper := proj.PriceEqual(9100)
+	if len(cheaper.TruthyIndices()) != 2 {
+		t.Fatalf("projection missed update")
+	}
+	proj.Merge()
+	for i := 1; i < proj.Length(); i++ {
+		if proj.Access(i-1).Price > proj.Access(i).Price {
+			t.Fatalf("projection out of order at %v", i)
+		}
+	}
+	if proj.Length() != 12 || proj.Access(proj.Length()-1).Price != 9400 {
+		t.Fatalf("projection missed update")
+	}
+
+	// Values must suit the field
+	if _, err := db.Update(query, FieldPrice, "9100"); err == nil {
+		t.Fatalf("accepted mismatched value")
+	}
+}
+
+func TestUpdateStrings(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(SetNameTimeSpec)
+	size := db.Sets.Dictionary().Size()
+
+	// A new set grows the shared dictionary
+	query := db.Sets.Equal("Onslaught Foil")
+	if _, err := db.Update(query, FieldSet, "Onslaught Premium"); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if db.Sets.Dictionary().Size() != size+1 {
+		t.Fatalf("dictionary did not grow")
+	}
+	premium := db.Sets.Equal("Onslaught Premium")
+	if len(premium.TruthyIndices()) != 1 {
+		t.Fatalf("update not visible")
+	}
+
+	premium = proj.SetEqual("Onslaught Premium")
+	if len(premium.TruthyIndices()) != 1 {
+		t.Fatalf("projection missed update")
+	}
+
+	// Deleted rows are left alone
+	db.Delete(db.Names.Equal("Griselbrand"))
+	updated, err := db.Update(allRows(db.Prices.Length()), FieldName, "Aether Vial")
+	if err != nil || updated != 7 {
+		t.Fatalf("bad update, %v %v", updated, err)
+	}
+}
+
+func TestUpdateKey(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	if err := db.EnableKeyIndex(DuplicateReject); err != nil {
+		t.Fatalf("failed to enable key index: %v", err)
+	}
+	day := func(d int) time.Time {
+		return time.Date(2016, time.March, d, 3, 51, 45, 0, time.UTC)
+	}
+
+	err := db.UpdateKey("Griselbrand", "Avacyn Restored", day(2),
+		FieldPrice, uint32(2050))
+	if err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	row, _ := db.Lookup("Griselbrand", "Avacyn Restored", day(2))
+	if db.Prices.Access(row) != 2050 {
+		t.Fatalf("update not visible")
+	}
+
+	// Moving a key onto an existing key is refused
+	err = db.UpdateKey("Griselbrand", "Avacyn Restored", day(2),
+		FieldTime, day(3))
+	if err == nil {
+		t.Fatalf("accepted duplicate key")
+	}
+
+	// Moving a key to a free slot updates the index
+	err = db.UpdateKey("Griselbrand", "Avacyn Restored", day(2),
+		FieldTime, day(4))
+	if err != nil {
+		t.Fatalf("failed to update key: %v", err)
+	}
+	if _, found := db.Lookup("Griselbrand", "Avacyn Restored", day(2)); found {
+		t.Fatalf("old key still indexed")
+	}
+	if moved, found := db.Lookup("Griselbrand", "Avacyn Restored", day(4)); !found || moved != row {
+		t.Fatalf("new key not indexed")
+	}
+
+	if err := db.UpdateKey("Aether Vial", "Darksteel", day(1),
+		FieldPrice, uint32(1)); err == nil {
+		t.Fatalf("updated missing key")
+	}
+}
+
+// Fields outside a projection's sort keys are overwritten in
+// place, unless a snapshot shares the projection's columns
+func TestUpdateProjectionsInPlace(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := db.Attach(NameTimeSpec)
+
+	if err := db.UpdateKey("Griselbrand", "Avacyn Restored", keyIndexDay(1),
+		FieldPrice, uint32(2050)); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if proj.MainLength() != 12 || proj.DeltaLength() != 0 || proj.LiveLength() != 12 {
+		t.Fatalf("update was not in place, %v with %v in delta",
+			proj.MainLength(), proj.DeltaLength())
+	}
+	updated := proj.PriceEqual(2050)
+	if indices := updated.TruthyIndices(); len(indices) != 1 ||
+		proj.Access(indices[0]).Set != "Avacyn Restored" {
+		t.Fatalf("projection missed update, %v", indices)
+	}
+
+	// With a snapshot sharing the columns the row moves
+	// through the delta, leaving the snapshot untouched
+	snap := db.Snapshot()
+	if err := db.UpdateKey("Griselbrand", "Avacyn Restored", keyIndexDay(1),
+		FieldPrice, uint32(2075)); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if proj.DeltaLength() != 1 || proj.LiveLength() != 12 {
+		t.Fatalf("shared update was in place, %v in delta", proj.DeltaLength())
+	}
+	copied := snap.AttachedProjections()[0]
+	if old := copied.PriceEqual(2050); len(old.TruthyIndices()) != 1 {
+		t.Fatalf("snapshot saw update")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"time"
+)
+
+// A function evaluated over a window of each row's neighbours
+type WindowFunction uint32
+
+const (
+	// 1 based position within the partition
+	RowNumber WindowFunction = iota
+	// Price Offset rows before
+	Lag
+	// Price Offset rows after
+	Lead
+	// Mean price over the frame, rounded down to the cent
+	MovingAverage
+	// Lowest price up to and including the row
+	RunningMin
+	// Highest price up to and including the row
+	RunningMax
+)
+
+// Declares a window function evaluated over a NameTimeProjection
+//
+// Partitions are a card in a particular set, ordered by time,
+// which is how the projection is already laid out.
+type WindowSpec struct {
+	Function WindowFunction
+
+	// Rows to look back or ahead, for Lag and Lead
+	Offset int
+
+	// Frame for MovingAverage, either the preceding Rows
+	// rows including the current one or, when Range is set,
+	// every row within Range before the current one
+	Rows  int
+	Range time.Duration
+}
+
+// Values of a window function, one per position of the
+// projection it was evaluated against
+type WindowResult struct {
+	Values UInt32Column
+
+	// Rows which have a value, Lag and Lead run off
+	// the edges of their partitions
+	Valid BoolColumn
+}
+
+// Evaluate a window function against every row
+//
+// Results are positional, so may be combined with any
+// BoolColumn produced by the same projection.
+func (proj *NameTimeProjection) Window(spec WindowSpec) WindowResult {
+	length := proj.Length()
+	values := make([]uint32, length)
+	valid := NewBoolColumn()
+
+	// Ensure the result spans the whole projection
+	valid.PushFalse(length)
+
+	proj.eachName(func(name string, positions []int) {
+		// Split the card into a partition per set,
+		// each remaining in time order
+		partitions := make(map[string][]int)
+		sets := make([]string, 0)
+		for _, p := range positions {
+			set := proj.setAt(p)
+			if _, ok := partitions[set]; !ok {
+				sets = append(sets, set)
+			}
+			partitions[set] = append(partitions[set], p)
+		}
+
+		for _, set := range sets {
+			proj.evaluateWindow(spec, partitions[set], values, &valid)
+		}
+	})
+
+	result := WindowResult{
+		Values: NewUInt32Column(),
+		Valid:  valid,
+	}
+	result.Values.Push(values)
+
+	return result
+}
+
+// Evaluate a window function over a single partition,
+// given as positions in time order
+func (proj *NameTimeProjection) evaluateWindow(spec WindowSpec,
+	partition []int, values []uint32, valid *BoolColumn) {
+
+	switch spec.Function {
+	case RowNumber:
+		for i, p := range partition {
+			values[p] = uint32(i + 1)
+			valid.Set(p)
+		}
+
+	case Lag, Lead:
+		offset := spec.Offset
+		if spec.Function == Lag {
+			offset = -offset
+		}
+		for i, p := range partition {
+			other := i + offset
+			if other < 0 || other >= len(partition) {
+				continue
+			}
+			values[p] = proj.priceAt(partition[other])
+			valid.Set(p)
+		}
+
+	case MovingAverage:
+		// Slide a frame [first, i] across the partition,
+		// keeping a running sum of the prices within it
+		var sum uint64
+		first := 0
+		for i, p := range partition {
+			sum += uint64(proj.priceAt(p))
+
+			if spec.Range > 0 {
+				cutoff := proj.timeAt(p).Add(-spec.Range)
+				for !proj.timeAt(partition[first]).After(cutoff) {
+					sum -= uint64(proj.priceAt(partition[first]))
+					first++
+				}
+			} else if spec.Rows > 0 && i-first+1 > spec.Rows {
+				sum -= uint64(proj.priceAt(partition[first]))
+				first++
+			}
+
+			values[p] = uint32(sum / uint64(i-first+1))
+			valid.Set(p)
+		}
+
+	case RunningMin, RunningMax:
+		var running uint32
+		for i, p := range partition {
+			price := proj.priceAt(p)
+			if i == 0 ||
+				(spec.Function == RunningMin && price < running) ||
+				(spec.Function == RunningMax && price > running) {
+				running = price
+			}
+			values[p] = running
+			valid.Set(p)
+		}
+	}
+}
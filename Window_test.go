@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Find the positions of a card in a set, in time order
+func partitionPositions(proj NameTimeProjection, name, set string) []int {
+	start, end := proj.KeyRange(name)
+
+	positions := make([]int, 0)
+	for i := start; i < end; i++ {
+		if proj.Sets.Access(i) == set {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// Evaluate every window function over Griselbrand's
+// Avacyn Restored prices of 2100, 2000 then 2200
+//
+// Postgres equivalent
+//  SELECT lag(price) OVER (PARTITION BY name, set ORDER BY time)
+//  	FROM prices.mtgprice;
+func TestNameTimeProjectionWindow(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	positions := partitionPositions(proj, "Griselbrand", "Avacyn Restored")
+	if len(positions) != 3 {
+		t.Fatalf("found %v positions, expected 3", len(positions))
+	}
+
+	// Zero marks rows with no value
+	cases := []struct {
+		spec     WindowSpec
+		expected []uint32
+	}{
+		{WindowSpec{Function: RowNumber}, []uint32{1, 2, 3}},
+		{WindowSpec{Function: Lag, Offset: 1}, []uint32{0, 2100, 2000}},
+		{WindowSpec{Function: Lead, Offset: 1}, []uint32{2000, 2200, 0}},
+		{WindowSpec{Function: Lag, Offset: 2}, []uint32{0, 0, 2100}},
+		{WindowSpec{Function: MovingAverage, Rows: 2}, []uint32{2100, 2050, 2100}},
+		{WindowSpec{Function: MovingAverage, Range: 36 * time.Hour},
+			[]uint32{2100, 2050, 2100}},
+		{WindowSpec{Function: MovingAverage, Range: 72 * time.Hour},
+			[]uint32{2100, 2050, 2100}},
+		{WindowSpec{Function: RunningMin}, []uint32{2100, 2000, 2000}},
+		{WindowSpec{Function: RunningMax}, []uint32{2100, 2100, 2200}},
+	}
+
+	for n, c := range cases {
+		result := proj.Window(c.spec)
+		if result.Values.Length() != proj.Length() {
+			t.Fatalf("case %v: result has %v values for %v rows",
+				n, result.Values.Length(), proj.Length())
+		}
+
+		for i, p := range positions {
+			hasValue := result.Valid.contents.Test(uint(p))
+			if hasValue != (c.expected[i] != 0) {
+				t.Fatalf("case %v: validity wrong at %v", n, i)
+			}
+			if hasValue && result.Values.Access(p) != c.expected[i] {
+				t.Fatalf("case %v: found %v at %v, expected %v",
+					n, result.Values.Access(p), i, c.expected[i])
+			}
+		}
+	}
+}
+
+// Partitions never leak into one another
+func TestNameTimeProjectionWindowPartitions(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	proj := NameTimeProjectionFromPriceDB(db)
+
+	result := proj.Window(WindowSpec{Function: Lag, Offset: 1})
+
+	// Only the first day of each card and set lacks a value,
+	// five partitions across twelve rows
+	valid := len(result.Valid.TruthyIndices())
+	if valid != proj.Length()-5 {
+		t.Fatalf("found %v rows with a value, expected %v",
+			valid, proj.Length()-5)
+	}
+}
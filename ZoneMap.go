@@ -0,0 +1,201 @@
+package main
+
+import (
+	"time"
+
+	"github.com/willf/bitset"
+)
+
+// Bounds on the values of a single row group
+//
+// Bounds only ever widen, so after an update they may be looser
+// than the values themselves but never tighter.
+type UInt32Zone struct {
+	Min, Max uint32
+}
+
+func (z *UInt32Zone) widen(v uint32) {
+	if v < z.Min {
+		z.Min = v
+	}
+	if v > z.Max {
+		z.Max = v
+	}
+}
+
+// Bounds on the times of a single row group
+//
+// Times are kept whole rather than as nanoseconds since epoch,
+// which cannot represent times far from it.
+type TimeZone struct {
+	Min, Max time.Time
+}
+
+func (z *TimeZone) widen(v time.Time) {
+	if v.Before(z.Min) {
+		z.Min = v
+	}
+	if v.After(z.Max) {
+		z.Max = v
+	}
+}
+
+// How many rows of a row group a predicate can match,
+// as far as its zone tells
+type zoneMatch uint32
+
+const (
+	// The group must be scanned
+	zoneSome zoneMatch = iota
+	// No row can match, the group is skipped
+	zoneNone
+	// Every row matches, the group is filled without scanning
+	zoneAll
+)
+
+// Decide how a comparison against a zone's bounds goes
+func (z UInt32Zone) less(v uint32) zoneMatch {
+	switch {
+	case z.Min >= v:
+		return zoneNone
+	case z.Max < v:
+		return zoneAll
+	}
+	return zoneSome
+}
+func (z UInt32Zone) more(v uint32) zoneMatch {
+	switch {
+	case z.Max < v:
+		return zoneNone
+	case z.Min >= v:
+		return zoneAll
+	}
+	return zoneSome
+}
+func (z UInt32Zone) equal(v uint32) zoneMatch {
+	switch {
+	case v < z.Min || v > z.Max:
+		return zoneNone
+	case z.Min == v && z.Max == v:
+		return zoneAll
+	}
+	return zoneSome
+}
+func (z TimeZone) after(when time.Time) zoneMatch {
+	switch {
+	case !z.Max.After(when):
+		return zoneNone
+	case z.Min.After(when):
+		return zoneAll
+	}
+	return zoneSome
+}
+func (z TimeZone) before(when time.Time) zoneMatch {
+	switch {
+	case !z.Min.Before(when):
+		return zoneNone
+	case z.Max.Before(when):
+		return zoneAll
+	}
+	return zoneSome
+}
+
+// Set the bits of rows [0, n) in words, the equivalent
+// of PushTrue for words being filled in place
+func fillWords(words []uint64, n int) {
+	for w := range words {
+		if remaining := n - w*64; remaining < 64 {
+			words[w] = 1<<uint(remaining) - 1
+		} else {
+			words[w] = ^uint64(0)
+		}
+	}
+}
+
+// Evaluate a predicate over a row group of n rows, consulting
+// its zone before running scan to fill words
+//
+// Skipped groups are left as the false words they arrived as.
+func packZone(match zoneMatch, words []uint64, n int, scan func()) {
+	switch match {
+	case zoneNone:
+	case zoneAll:
+		fillWords(words, n)
+	default:
+		scan()
+	}
+}
+
+// Narrow a result over a row group, consulting its zone
+// before scanning
+func andZone(match zoneMatch, words []uint64, n int, test func(i int) bool) {
+	switch match {
+	case zoneNone:
+		for w := range words {
+			words[w] = 0
+		}
+	case zoneAll:
+	default:
+		andWords(words, n, test)
+	}
+}
+
+// Codes present in a single row group of a dictionary column
+//
+// As with bounds, codes are never removed by updates.
+type presentCodes struct {
+	codes *bitset.BitSet
+	count int
+}
+
+func newPresentCodes() *presentCodes {
+	return &presentCodes{codes: bitset.New(0)}
+}
+
+func (p *presentCodes) add(code uint32) {
+	if !p.codes.Test(uint(code)) {
+		p.codes.Set(uint(code))
+		p.count++
+	}
+}
+
+func (p *presentCodes) clone() *presentCodes {
+	return &presentCodes{codes: p.codes.Clone(), count: p.count}
+}
+
+// Decide how an equality test against a group's codes goes
+func (p *presentCodes) equal(code uint32) zoneMatch {
+	switch {
+	case !p.codes.Test(uint(code)):
+		return zoneNone
+	case p.count == 1:
+		return zoneAll
+	}
+	return zoneSome
+}
+
+// Decide how a membership test against a group's codes goes
+func (p *presentCodes) within(codes []uint32) zoneMatch {
+	found := 0
+	for _, code := range codes {
+		if p.codes.Test(uint(code)) {
+			found++
+		}
+	}
+
+	switch {
+	case found == 0:
+		return zoneNone
+	case found == p.count:
+		return zoneAll
+	}
+	return zoneSome
+}
+
+// Access the zone map of a column, one zone per row group
+func (c *UInt32Column) Zones() []UInt32Zone {
+	return c.zones
+}
+func (c *TimeColumn) Zones() []TimeZone {
+	return c.zones
+}
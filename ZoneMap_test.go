@@ -0,0 +1,180 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Three row groups holding disjoint ascending prices with a
+// constant middle group, so predicates hit skipped, filled
+// and scanned groups
+func setupZonedColumn() (UInt32Column, []uint32) {
+	values := make([]uint32, RowGroupSize*2+100)
+	for i := range values {
+		switch {
+		case i < RowGroupSize:
+			values[i] = uint32(i % 1000)
+		case i < RowGroupSize*2:
+			values[i] = 5000
+		default:
+			values[i] = uint32(9000 + i%100)
+		}
+	}
+
+	col := NewUInt32Column()
+	col.Push(values)
+
+	return col, values
+}
+
+func TestZoneMapBounds(t *testing.T) {
+	col, _ := setupZonedColumn()
+
+	expected := []UInt32Zone{{0, 999}, {5000, 5000}, {9000, 9099}}
+	zones := col.Zones()
+	if len(zones) != len(expected) {
+		t.Fatalf("bad zone count, %v", len(zones))
+	}
+	for g, zone := range zones {
+		if zone != expected[g] {
+			t.Fatalf("bad zone %v, %v != %v", g, zone, expected[g])
+		}
+	}
+
+	// Updates only widen
+	col.Set(RowGroupSize+1, 7)
+	if zone := col.Zones()[1]; zone != (UInt32Zone{7, 5000}) {
+		t.Fatalf("update did not widen zone, %v", zone)
+	}
+	col.Set(RowGroupSize+1, 5000)
+	if zone := col.Zones()[1]; zone != (UInt32Zone{7, 5000}) {
+		t.Fatalf("update narrowed zone, %v", zone)
+	}
+}
+
+// Skipping and filling groups must agree with a plain scan
+func TestZoneMapPredicates(t *testing.T) {
+	col, values := setupZonedColumn()
+
+	check := func(name string, results BoolColumn, expect func(v uint32) bool) {
+		if results.end != uint(len(values)) {
+			t.Fatalf("%v: bad length, %v", name, results.end)
+		}
+		for i, v := range values {
+			if results.contents.Test(uint(i)) != expect(v) {
+				t.Fatalf("%v: wrong result at %v", name, i)
+			}
+		}
+		if results.contents.Test(uint(len(values))) {
+			t.Fatalf("%v: filled past the end", name)
+		}
+	}
+
+	check("none", col.More(9000000), func(v uint32) bool { return v >= 9000000 })
+	check("all", col.Less(9000000), func(v uint32) bool { return v < 9000000 })
+	check("filled", col.Equal(5000), func(v uint32) bool { return v == 5000 })
+	check("mixed", col.More(500), func(v uint32) bool { return v >= 500 })
+	check("middle", col.Less(5001), func(v uint32) bool { return v < 5001 })
+}
+
+func TestZoneMapTimes(t *testing.T) {
+	start := time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC)
+	col := NewTimeColumn()
+	times := make([]time.Time, RowGroupSize+10)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * time.Second)
+	}
+	col.Push(times)
+
+	if zone := col.Zones()[1]; !zone.Min.Equal(times[RowGroupSize]) {
+		t.Fatalf("bad time zone, %v", zone)
+	}
+
+	// The first group is skipped entirely, the second filled
+	after := col.After(times[RowGroupSize-1])
+	indices := after.TruthyIndices()
+	if len(indices) != 10 || indices[0] != RowGroupSize {
+		t.Fatalf("bad after, %v", len(indices))
+	}
+
+	// Narrowing clears skipped groups and keeps filled ones
+	between := col.Before(times[RowGroupSize+5])
+	col.ANDAfter(times[RowGroupSize-1], between)
+	indices = between.TruthyIndices()
+	if len(indices) != 5 || indices[0] != RowGroupSize {
+		t.Fatalf("bad between, %v", indices)
+	}
+}
+
+// Bounds far outside what nanoseconds since epoch can hold
+// must still skip and fill groups correctly
+func TestZoneMapTimesFarBounds(t *testing.T) {
+	db := setupSyntheticPriceDB()
+	past := time.Date(1500, time.January, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	checks := []struct {
+		name     string
+		results  BoolColumn
+		expected int
+	}{
+		{"after future", db.Times.After(future), 0},
+		{"before future", db.Times.Before(future), 12},
+		{"after past", db.Times.After(past), 12},
+		{"before past", db.Times.Before(past), 0},
+	}
+	for _, check := range checks {
+		if found := len(check.results.TruthyIndices()); found != check.expected {
+			t.Fatalf("%v found %v rows, expected %v",
+				check.name, found, check.expected)
+		}
+	}
+}
+
+func TestZoneMapPresentCodes(t *testing.T) {
+	col := NewFiniteString32Column()
+	names := make([]string, RowGroupSize+10)
+	for i := range names {
+		names[i] = "Griselbrand"
+		if i >= RowGroupSize {
+			names[i] = "Windswept Heath"
+		}
+	}
+	names[3] = "Avacyn, Angel of Hope"
+	col.Push(names)
+
+	heath := col.Equal("Windswept Heath")
+	if indices := heath.TruthyIndices(); len(indices) != 10 || indices[0] != RowGroupSize {
+		t.Fatalf("bad equal, %v", len(indices))
+	}
+	avacyn := col.Equal("Avacyn, Angel of Hope")
+	if indices := avacyn.TruthyIndices(); len(indices) != 1 || indices[0] != 3 {
+		t.Fatalf("bad equal, %v", indices)
+	}
+
+	// A new code in a uniform group forces a scan
+	col.Set(RowGroupSize+2, "Griselbrand")
+	heath = col.Equal("Windswept Heath")
+	if indices := heath.TruthyIndices(); len(indices) != 9 {
+		t.Fatalf("bad equal after update, %v", len(indices))
+	}
+	griselbrand := col.Equal("Griselbrand")
+	if indices := griselbrand.TruthyIndices(); len(indices) != RowGroupSize {
+		t.Fatalf("bad equal after update, %v", len(indices))
+	}
+
+	// Membership fills the first group, which holds only the
+	// values, and scans the second, repeats are ignored
+	within := col.Within([]string{"Griselbrand", "Zzz",
+		"Avacyn, Angel of Hope", "Griselbrand"})
+	if indices := within.TruthyIndices(); len(indices) != RowGroupSize+1 ||
+		indices[RowGroupSize] != RowGroupSize+2 {
+		t.Fatalf("bad within, %v", len(indices))
+	}
+	if match := col.present[0].within([]uint32{1, 2}); match != zoneAll {
+		t.Fatalf("group holding only the values not filled, %v", match)
+	}
+	if none := col.Within([]string{"Zzz"}); len(none.TruthyIndices()) != 0 {
+		t.Fatalf("found rows for a missing value")
+	}
+}
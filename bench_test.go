@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"testing"
 
 	"time"
@@ -12,6 +13,7 @@ var garbage PriceDB
 var uselessTuples []PriceTuple
 var garbageQuery BoolColumn
 var trashUint64 uint64
+var garbageProjection Projection
 
 var BenchDB *PriceDB
 var BenchNameTimeProjection *NameTimeProjection
@@ -136,6 +138,23 @@ func BenchmarkFiniteString32Within(b *testing.B) {
 	}
 }
 
+// As BenchmarkFiniteString32Within, answered from a bitmap index
+func BenchmarkFiniteString32WithinIndexed(b *testing.B) {
+	db := setupPriceBenchmark(b)
+	db.Names.BuildIndex()
+
+	names := []string{
+		"Griselbrand",
+		"Avacyn, Angel of Hope",
+	}
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		garbageQuery = db.Names.Within(names)
+	}
+}
+
 // Select all prices more than 100 cents = $1 and
 // rematerialize them into tuples
 func BenchmarkSelectAllMoreThanDollarMaterial(b *testing.B) {
@@ -339,3 +358,120 @@ func BenchmarkLatestHighestPriceMaterialOld(b *testing.B) {
 		uselessTuples = []PriceTuple{found}
 	}
 }
+
+// Build the name then time sorted projection from scratch
+func BenchmarkNameTimeProjectionBuild(b *testing.B) {
+	db := setupPriceBenchmark(b)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		proj := NameTimeProjectionFromPriceDB(db)
+		BenchNameTimeProjection = &proj
+	}
+}
+
+// Select the latest group of prices for every card
+func BenchmarkLatestAll(b *testing.B) {
+	proj := setupNameTimeProjectionBench(b)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for _, group := range proj.LatestAll() {
+			uselessTuples = group
+		}
+	}
+}
+
+// Synthetic values for kernel benchmarks, which need no
+// price data on disk
+var kernelBenchValues = kernelTestValues(RowGroupSize * 16)
+
+// The per row loop predicates were originally built from
+func BenchmarkKernelLessPerRow(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		results := NewBoolColumn()
+		for _, v := range kernelBenchValues {
+			results.Push([]bool{v < 1<<31})
+		}
+		garbageQuery = results
+	}
+}
+
+func BenchmarkKernelLessGeneric(b *testing.B) {
+	words := make([]uint64, len(kernelBenchValues)/64)
+	for n := 0; n < b.N; n++ {
+		lessWordsGeneric(kernelBenchValues, 1<<31, words)
+	}
+}
+
+func BenchmarkKernelLessVectorized(b *testing.B) {
+	words := make([]uint64, len(kernelBenchValues)/64)
+	for n := 0; n < b.N; n++ {
+		lessWords(kernelBenchValues, 1<<31, words)
+	}
+}
+
+func BenchmarkKernelEqualVectorized(b *testing.B) {
+	words := make([]uint64, len(kernelBenchValues)/64)
+	for n := 0; n < b.N; n++ {
+		equalWords(kernelBenchValues, 1<<31, words)
+	}
+}
+
+func BenchmarkKernelSumGeneric(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		trashUint64 = sumWordsGeneric(kernelBenchValues)
+	}
+}
+
+func BenchmarkKernelSumVectorized(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		trashUint64 = sumWords(kernelBenchValues)
+	}
+}
+
+// Unpack long runs as RLE predicates do
+func BenchmarkBoolPushRuns(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		results := NewBoolColumn()
+		for run := 0; run < 1000; run++ {
+			results.PushTrue(700)
+			results.PushFalse(300)
+		}
+		garbageQuery = results
+	}
+}
+
+// A million synthetic rows scraped in time order, so builds
+// need no price data on disk
+func setupSyntheticBuildBench() PriceDB {
+	start := time.Date(2016, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tuples := make([]PriceTuple, 1<<20)
+	for i := range tuples {
+		card := (i * 7919) % 20000
+		tuples[i] = PriceTuple{
+			Name:  fmt.Sprintf("card %v", card),
+			Set:   fmt.Sprintf("set %v", card%300),
+			Price: uint32((i * 2654435761) % 10000),
+			Time:  start.Add(time.Duration(i/20000) * time.Hour),
+		}
+	}
+
+	db := NewPriceDB()
+	db.Push(tuples)
+
+	return db
+}
+
+// Build the set, name then time sorted projection
+// from a million synthetic rows
+func BenchmarkProjectionBuildSynthetic(b *testing.B) {
+	db := setupSyntheticBuildBench()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		garbageProjection = ProjectionFromPriceDB(db, SetNameTimeSpec)
+	}
+}
@@ -19,7 +19,7 @@ import (
 
 	"runtime"
 
-	"github.com/bjwbell/gensimd/simd"
+	"sync"
 )
 
 // This is really just documentation at this point...
@@ -28,6 +28,12 @@ type Column interface {
 }
 
 // A toy price database
+//
+// Any number of readers may run alongside a single writer.
+// Methods which modify the database lock it themselves, every
+// other access, including to columns directly, must happen
+// inside View or against a Snapshot to be safe against a
+// concurrent writer.
 type PriceDB struct {
 	Names FiniteString32Column
 	Sets  FiniteString32Column
@@ -35,15 +41,57 @@ type PriceDB struct {
 	Prices UInt32Column
 
 	Times TimeColumn
+
+	// Projections kept current as we ingest and
+	// considered when planning queries
+	projections *ProjectionRegistry
+
+	// Index of (name, set, time) keys, nil until enabled
+	keys *KeyIndex
+
+	// Rows deleted but not yet compacted away
+	deleted *DeleteVector
+
+	// Shared by copies so they exclude each other
+	lock *sync.RWMutex
+
+	// Whether snapshots may share our columns, and
+	// whether we are one
+	frozen, snapshot bool
 }
 
 func NewPriceDB() PriceDB {
-	return PriceDB{
-		Names:  NewFiniteString32Column(),
-		Sets:   NewFiniteString32Column(),
+	return NewPriceDBSharing(NewDictionary(), NewDictionary())
+}
+
+// Create a database encoding names and sets with existing
+// dictionaries, typically those of a table it will be joined with
+func NewPriceDBSharing(names, sets *Dictionary) PriceDB {
+	db := PriceDB{
+		Names:  NewFiniteString32ColumnSharing(names),
+		Sets:   NewFiniteString32ColumnSharing(sets),
 		Prices: NewUInt32Column(),
 		Times:  NewTimeColumn(),
+
+		projections: &ProjectionRegistry{},
+
+		lock: &sync.RWMutex{},
 	}
+	db.attachDeleteVector(NewDeleteVector())
+
+	return db
+}
+
+// Run a read against the database, excluding writers
+//
+// Any number of views may run at once. Writing to the
+// database from within a view deadlocks, as does nesting views
+// while a writer waits.
+func (db *PriceDB) View(fn func()) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	fn()
 }
 
 // Materialize all PriceTuples that are truthy from
@@ -64,6 +112,26 @@ func (db *PriceDB) MaterializeFromBools(b BoolColumn) []PriceTuple {
 	// would work better to blacklist against. Oh well.
 	positions := b.TruthyIndices()
 
+	// Deleted rows never make it out
+	if db.deleted.Count() > 0 {
+		live := positions[:0]
+		for _, p := range positions {
+			if !db.deleted.Deleted(p) {
+				live = append(live, p)
+			}
+		}
+		positions = live
+	}
+
+	return db.MaterializePositions(positions)
+}
+
+// Materialize the PriceTuples stored at each of the provided
+// positions, in the order the positions are provided
+//
+// As with MaterializeFromBools, we do no range checking.
+func (db *PriceDB) MaterializePositions(positions []int) []PriceTuple {
+
 	// Keep columns separate for as long as possible
 	names := make([]string, len(positions))
 	sets := make([]string, len(positions))
@@ -92,7 +160,9 @@ func (db *PriceDB) MaterializeFromBools(b BoolColumn) []PriceTuple {
 }
 
 // Materialize all PriceTuples that are truthy from the provided
-// BoolColumn then sort them in descending order of time.
+// BoolColumn then sort them in ascending order of time.
+//
+// For any other ordering, see OrderBy and TopK.
 func (db *PriceDB) MaterializeTimeSortAsc(b BoolColumn) []PriceTuple {
 
 	// Materialize
@@ -137,19 +207,37 @@ func (db *PriceDB) IngestCSV(file string) error {
 		tuples = append(tuples, tuple)
 
 		if len(tuples) >= 4096 {
-			db.Push(tuples)
+			if err := db.Push(tuples); err != nil {
+				return err
+			}
 			tuples = make([]PriceTuple, 0)
 		}
 
 	}
 
 	// Clear off the remaining tuples
-	db.Push(tuples)
-
-	return nil
+	return db.Push(tuples)
 }
 
-func (db *PriceDB) Push(values []PriceTuple) {
+// Add a batch of tuples to the database
+//
+// With a key index enabled, tuples whose name, set and time
+// already exist are handled according to its DuplicatePolicy.
+// A rejected batch adds nothing.
+func (db *PriceDB) Push(values []PriceTuple) error {
+	db.lockWriter()
+	defer db.lock.Unlock()
+
+	var changes []rowChange
+	if db.keys != nil {
+		var err error
+		values, changes, err = db.keys.admit(db, values)
+		if err != nil {
+			return err
+		}
+	}
+	start := db.Prices.Length()
+
 	names := make([]string, len(values))
 	sets := make([]string, len(values))
 	prices := make([]uint32, len(values))
@@ -164,6 +252,18 @@ func (db *PriceDB) Push(values []PriceTuple) {
 	db.Sets.Push(sets)
 	db.Prices.Push(prices)
 	db.Times.Push(times)
+
+	if db.keys != nil {
+		db.keys.insert(db, start, len(values))
+	}
+
+	// Keep attached projections current
+	db.changeProjections(changes)
+	for _, proj := range db.projections.projections {
+		proj.Append(values)
+	}
+
+	return nil
 }
 
 type ColumnFlavor uint32
@@ -174,7 +274,7 @@ const (
 )
 
 func main() {
-	fmt.Println(simd.Available())
+	fmt.Println("vectorized kernels:", VectorizedKernels)
 
 	db := NewPriceDB()
 
@@ -25,6 +25,39 @@ func setupPriceTest(t *testing.T) PriceDB {
 	return db
 }
 
+// Build a tiny, fully known database for tests which
+// need exact expectations rather than prices.csv
+//
+// Three cards across two sets, each priced at three times.
+func setupSyntheticPriceDB() PriceDB {
+	db := NewPriceDB()
+	db.Push(syntheticPriceTuples())
+
+	return db
+}
+
+// The tuples making up setupSyntheticPriceDB in ingest order
+func syntheticPriceTuples() []PriceTuple {
+	day := func(d int) time.Time {
+		return time.Date(2016, time.March, d, 3, 51, 45, 0, time.UTC)
+	}
+
+	return []PriceTuple{
+		{Name: "Griselbrand", Set: "Avacyn Restored", Price: 2100, Time: day(1)},
+		{Name: "Windswept Heath", Set: "Onslaught", Price: 9000, Time: day(1)},
+		{Name: "Avacyn, Angel of Hope", Set: "Avacyn Restored", Price: 3000, Time: day(1)},
+		{Name: "Griselbrand", Set: "Avacyn Restored Foil", Price: 5200, Time: day(1)},
+		{Name: "Windswept Heath", Set: "Onslaught", Price: 9500, Time: day(2)},
+		{Name: "Griselbrand", Set: "Avacyn Restored", Price: 2000, Time: day(2)},
+		{Name: "Avacyn, Angel of Hope", Set: "Avacyn Restored", Price: 3100, Time: day(2)},
+		{Name: "Griselbrand", Set: "Avacyn Restored Foil", Price: 5523, Time: day(2)},
+		{Name: "Windswept Heath", Set: "Onslaught", Price: 9400, Time: day(3)},
+		{Name: "Avacyn, Angel of Hope", Set: "Avacyn Restored", Price: 2900, Time: day(3)},
+		{Name: "Griselbrand", Set: "Avacyn Restored", Price: 2200, Time: day(3)},
+		{Name: "Windswept Heath", Set: "Onslaught Foil", Price: 15499, Time: day(3)},
+	}
+}
+
 // Select all prices more than than 90 000 000 cents = $900K
 //
 // This should always return 0 results